@@ -4,7 +4,10 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"github.com/clearbank/terrapolicy/internals/policies"
+	"github.com/clearbank/terrapolicy"
+	"github.com/clearbank/terrapolicy/plugin"
+	"github.com/clearbank/terrapolicy/policies"
+	"gopkg.in/yaml.v3"
 	"log"
 	"os"
 )
@@ -18,14 +21,29 @@ func main() {
 	assert_success(err)
 
 	log.Printf("%v", args.Config)
-	policy, err := policies.Parse(args.Config)
+	defer plugin.Cleanup()
+	assert_success(terrapolicy.LoadPlugins(args.Config))
+
+	policy, problems, err := policies.Lint(args.Config, terrapolicy.Schemas())
 	assert_success(err)
 
-	fmt.Printf("%+v\n", policy)
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+
+	if len(problems) > 0 {
+		plugin.Cleanup()
+		log.Fatalf("[ERROR]: %d problem(s) found\n", len(problems))
+	}
+
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	assert_success(encoder.Encode(policy))
 }
 
 func assert_success(e error) {
 	if e != nil {
+		plugin.Cleanup()
 		log.Fatalf("[ERROR]: %v\n", e)
 	}
 }
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"log"
+	"os"
+	"time"
+)
+
+type Args struct {
+	Dir    string
+	Prune  bool
+	MaxAge time.Duration
+}
+
+func main() {
+	args, err := initArgs()
+	assert_success(err)
+
+	if !args.Prune {
+		providers, err := tfschema.ListCache(args.Dir)
+		assert_success(err)
+
+		for _, provider := range providers {
+			fmt.Printf("%v\tlast used %v\n", provider, provider.LastUsed.Format(time.RFC3339))
+		}
+		return
+	}
+
+	pruned, err := tfschema.PruneCache(args.Dir, args.MaxAge, time.Now())
+	for _, provider := range pruned {
+		fmt.Printf("pruned %v\n", provider)
+	}
+	assert_success(err)
+}
+
+func assert_success(e error) {
+	if e != nil {
+		log.Fatalf("[ERROR]: %v\n", e)
+	}
+}
+
+func initArgs() (Args, error) {
+	args := Args{}
+	programName := os.Args[0]
+	programArgs := os.Args[1:]
+
+	fs := flag.NewFlagSet(programName, flag.ExitOnError)
+	fs.StringVar(&args.Dir, "dir", tfschema.DefaultCacheDir(), "The directory provider schemas are cached in")
+	fs.BoolVar(&args.Prune, "prune", false, "Removes the provider versions not used since -max-age. Lists the cached provider versions otherwise")
+	fs.DurationVar(&args.MaxAge, "max-age", 30*24*time.Hour, "The age of the provider versions removed by -prune. 0 removes every provider version")
+
+	if err := fs.Parse(programArgs); err != nil {
+		return args, err
+	}
+
+	if args.Dir == "" {
+		return args, fmt.Errorf("must pass a cache directory")
+	}
+
+	return args, nil
+}
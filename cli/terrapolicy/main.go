@@ -9,7 +9,9 @@ import (
 
 	"github.com/clearbank/terrapolicy"
 	"github.com/clearbank/terrapolicy/internals/cli"
-	"github.com/clearbank/terrapolicy/internals/policies"
+	"github.com/clearbank/terrapolicy/internals/report"
+	"github.com/clearbank/terrapolicy/plugin"
+	"github.com/clearbank/terrapolicy/policies"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/logutils"
 )
@@ -33,6 +35,7 @@ func initLogFiltering(verbose bool) {
 }
 
 func fail(e error) {
+	plugin.Cleanup()
 	log.Printf("\n[ERROR] execution failed due to error code: %v", e)
 	os.Exit(1)
 }
@@ -63,22 +66,71 @@ func main() {
 	}
 
 	initLogFiltering(args.Verbose)
-	policy, err := policies.Parse(args.Config)
+	defer plugin.Cleanup()
+
+	if err := terrapolicy.LoadPlugins(args.Config); err != nil {
+		fail(err)
+	}
+
+	policy, err := policies.Parse(args.Config, terrapolicy.Schemas())
 
 	if err != nil {
 		fail(err)
 	}
 
-	err = terrapolicy.TerraPolicy(terrapolicy.Args{
-		Policy: policy,
-		Flags:  policies.PolicyExecutionFlags{Strict: args.Strict},
-		Dir:    args.Dir,
+	if args.Waivers != "" {
+		waivers, err := policies.ParseWaivers(args.Waivers)
+		if err != nil {
+			fail(err)
+		}
+		policy.Waivers = append(policy.Waivers, waivers...)
+	}
+
+	terrapolicy.SetSchemaCache(args.SchemaCache)
+
+	result, err := terrapolicy.TerraPolicy(terrapolicy.Args{
+		Policy:        policy,
+		Flags:         args.ExecutionFlags(),
+		Dir:           args.Dir,
+		DryRun:        args.DryRun,
+		WriteStrategy: args.WriteStrategy,
+		Out:           args.Out,
+		FailOn:        args.FailOn,
+		Parallelism:   args.Parallelism,
+		SchemaFile:    args.SchemaFile,
 	})
 
+	if args.DryRun {
+		printDiffs(args.DiffOutput(), result.Changes)
+	}
+
+	if args.Report != "" {
+		if reportErr := report.Write(args.Report, args.ReportFile, report.ReportPayload{
+			Policy:       policy,
+			Findings:     result.Findings,
+			StaleWaivers: result.StaleWaivers,
+			Version:      version,
+			FailOn:       args.FailOn,
+		}); reportErr != nil {
+			fail(reportErr)
+		}
+	}
+
 	if err != nil {
 		fail(err)
 	} else {
-		log.Printf("[INFO] completed")
+		log.Printf("[INFO] completed in %v", result.Timing.Total)
+	}
+}
+
+func printDiffs(w io.Writer, changes []terrapolicy.FileChange) {
+	for _, change := range changes {
+		diff, err := change.Diff()
+		if err != nil {
+			log.Printf("[ERROR] cannot compute diff for %v: %v", change.Path, err)
+			continue
+		}
+		fmt.Fprint(w, diff)
 	}
 }
 
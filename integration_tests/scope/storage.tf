@@ -0,0 +1,7 @@
+resource "azurerm_storage_account" "test" {
+  name                     = "mockstorageaccount"
+  resource_group_name      = "mock"
+  location                 = "uksouth"
+  account_tier             = "Standard"
+  account_replication_type = "LRS"
+}
@@ -3,23 +3,40 @@ package cli
 import (
 	"errors"
 	"flag"
+	"io"
+	"os"
 
 	"github.com/clearbank/terrapolicy/internals/file"
+	"github.com/clearbank/terrapolicy/internals/report"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"github.com/clearbank/terrapolicy/policies"
 )
 
 type Args struct {
-	Config  string
-	Strict  bool
-	Verbose bool
-	Dir     string
-	Help    bool
-	Version bool
+	Config               string
+	Strict               bool
+	Verbose              bool
+	Dir                  string
+	Help                 bool
+	Version              bool
+	Report               string
+	ReportFile           string
+	DryRun               bool
+	WriteStrategy        string
+	Out                  string
+	FailOn               policies.Severity
+	DisallowSuppressions bool
+	Waivers              string
+	Parallelism          int
+	SchemaCache          string
+	SchemaFile           string
 }
 
 var TERRAPOLICY_DEFAULT_POLICY_NAME = ".terrapolicy.yaml"
 
 func ParseArgs(programName string, programArgs []string) (Args, error) {
 	args := Args{}
+	var failOn string
 
 	fs := flag.NewFlagSet(programName, flag.ContinueOnError)
 	fs.StringVar(&args.Config, "config", "", "The locations of the yaml policy")
@@ -28,6 +45,17 @@ func ParseArgs(programName string, programArgs []string) (Args, error) {
 	fs.BoolVar(&args.Help, "help", false, "Usage")
 	fs.StringVar(&args.Dir, "dir", ".", "cwd")
 	fs.BoolVar(&args.Version, "version", false, "Prints the version")
+	fs.StringVar(&args.Report, "report", "", "The format of the report of every policy evaluation: json,sarif,junit")
+	fs.StringVar(&args.ReportFile, "report-file", "", "The location of the report. Defaults to stdout")
+	fs.StringVar(&args.WriteStrategy, "write-strategy", "", "How remediations are written: sidecar (default),in_place,mirror")
+	fs.StringVar(&args.Out, "out", "", "The output directory the root module is mirrored to by the mirror write strategy")
+	fs.StringVar(&failOn, "fail-on", "info", "The minimum severity of failed policies failing the execution: info,low,medium,high,critical")
+	fs.BoolVar(&args.DisallowSuppressions, "disallow-suppressions", false, "Ignores terrapolicy:ignore comments in terraform files")
+	fs.StringVar(&args.Waivers, "waivers", "", "The location of a yaml file of waivers, added to the waivers of the policy")
+	fs.IntVar(&args.Parallelism, "parallelism", 0, "The number of terraform files evaluated concurrently. Defaults to the number of CPUs")
+	fs.StringVar(&args.SchemaCache, "schema-cache", "", "The directory provider schemas are cached in, e.g. "+tfschema.DefaultCacheDir()+". Disabled by default")
+	fs.StringVar(&args.SchemaFile, "schema-file", "", "The location of the output of `terraform providers schema -json`, used instead of the provider plugins")
+	fs.BoolVar(&args.DryRun, "dry-run", false, "Prints a diff of the remediations instead of applying them. Fails if any remediation is pending")
 
 	err := fs.Parse(programArgs)
 
@@ -42,10 +70,30 @@ func ParseArgs(programName string, programArgs []string) (Args, error) {
 		return args, nil
 	}
 
+	if args.FailOn, err = policies.ParseSeverity(failOn); err != nil {
+		return args, errors.New("unknown_severity")
+	}
+
+	if args.Parallelism < 0 {
+		return args, errors.New("invalid_parallelism")
+	}
+
+	if args.Report != "" && !report.IsSupportedFormat(args.Report) {
+		return args, errors.New("unknown_report_format")
+	}
+
 	if args.Config != "" && !file.Exists(args.Config) {
 		return args, errors.New("config_not_found")
 	}
 
+	if args.Waivers != "" && !file.Exists(args.Waivers) {
+		return args, errors.New("waivers_not_found")
+	}
+
+	if args.SchemaFile != "" && !file.Exists(args.SchemaFile) {
+		return args, errors.New("schema_file_not_found")
+	}
+
 	if !file.Exists(args.Dir) {
 		return args, errors.New("dir_not_found")
 	}
@@ -58,3 +106,20 @@ func ParseArgs(programName string, programArgs []string) (Args, error) {
 
 	return args, nil
 }
+
+// ExecutionFlags returns the flags of the policy executions set by the arguments
+func (args Args) ExecutionFlags() policies.PolicyExecutionFlags {
+	return policies.PolicyExecutionFlags{
+		Strict:               args.Strict,
+		DisallowSuppressions: args.DisallowSuppressions,
+	}
+}
+
+// DiffOutput returns where the diffs of -dry-run are printed: stderr when the
+// report is written to stdout, so that the report stays valid
+func (args Args) DiffOutput() io.Writer {
+	if args.Report != "" && args.ReportFile == "" {
+		return os.Stderr
+	}
+	return os.Stdout
+}
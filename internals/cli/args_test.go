@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/policies"
+	"github.com/clearbank/terrapolicy/policies/resources"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	. "github.com/onsi/gomega"
+)
+
+const suppressedSource = `
+# terrapolicy:ignore=min-tls reason="approved exception"
+resource "azurerm_storage_account" "default" {
+  name = "default"
+}
+`
+
+func TestDisallowSuppressions(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, TERRAPOLICY_DEFAULT_POLICY_NAME), []byte("resources: []\n"), 0644)).To(Succeed())
+
+	execute := func(programArgs ...string) policies.PolicyOutcome {
+		args, err := ParseArgs("terrapolicy", append([]string{"-dir", dir}, programArgs...))
+		g.Expect(err).To(BeNil())
+
+		f, diags := hclwrite.ParseConfig([]byte(suppressedSource), "main.tf", hcl.InitialPos)
+		g.Expect(diags.HasErrors()).To(BeFalse())
+
+		results, err := (&resource_policies.AttributesPolicy{}).Execute(policies.ResourcePolicyPayload{
+			Hcl:    f,
+			Source: terraform.NewSourceIndex("main.tf", f),
+			Policy: policies.PolicyBlock{
+				Id:     "min-tls",
+				Type:   "attributes_policy",
+				Params: map[string]interface{}{"resource": "azurerm_storage_account", "attribute": "min_tls_version", "strategy": "fail_if_missing"},
+			},
+			Flags: args.ExecutionFlags(),
+		})
+		g.Expect(err).To(BeNil())
+		g.Expect(results).To(HaveLen(1))
+		return results[0].Outcome
+	}
+
+	g.Expect(execute()).To(Equal(policies.OUTCOME_SUPPRESSED))
+	g.Expect(execute("-disallow-suppressions")).To(Equal(policies.OUTCOME_FAIL))
+}
+
+func TestDiffOutput(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, TERRAPOLICY_DEFAULT_POLICY_NAME), []byte("resources: []\n"), 0644)).To(Succeed())
+
+	for programArgs, output := range map[string]*os.File{
+		"":                                   os.Stdout,
+		"-report json":                       os.Stderr,
+		"-report json -report-file out.json": os.Stdout,
+	} {
+		args, err := ParseArgs("terrapolicy", append([]string{"-dir", dir, "-dry-run"}, strings.Fields(programArgs)...))
+		g.Expect(err).To(BeNil())
+		g.Expect(args.DiffOutput()).To(Equal(output), programArgs)
+	}
+}
+
+func TestSchemaCacheOptIn(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, TERRAPOLICY_DEFAULT_POLICY_NAME), []byte("resources: []\n"), 0644)).To(Succeed())
+
+	args, err := ParseArgs("terrapolicy", []string{"-dir", dir})
+	g.Expect(err).To(BeNil())
+	g.Expect(args.SchemaCache).To(BeEmpty())
+
+	args, err = ParseArgs("terrapolicy", []string{"-dir", dir, "-schema-cache", filepath.Join(dir, "cache")})
+	g.Expect(err).To(BeNil())
+	g.Expect(args.SchemaCache).To(Equal(filepath.Join(dir, "cache")))
+}
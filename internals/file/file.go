@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/hcl/v2"
 	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/otiai10/copy"
+	"github.com/pmezard/go-difflib/difflib"
 	"go.uber.org/multierr"
 )
 
@@ -39,12 +40,66 @@ func ReplaceWithTerrapolicyFile(path string, textContent string, rename bool) er
 	return nil
 }
 
+func WriteFile(path string, textContent string) error {
+	return createFile(path, textContent)
+}
+
+// Mirror copies the content of dir into out. out is skipped when nested in dir, as
+// are the hidden directories, e.g. .terraform and .git, and the .bak backups
+func Mirror(dir string, out string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	absOut, err := filepath.Abs(out)
+	if err != nil {
+		return err
+	}
+
+	log.Print("[INFO] Mirroring ", dir, " to ", out)
+	return copy.Copy(dir, out, copy.Options{
+		Skip: func(srcinfo os.FileInfo, src, dest string) (bool, error) {
+			absSrc, err := filepath.Abs(src)
+			if err != nil || absSrc == absOut {
+				return true, err
+			}
+			if srcinfo.IsDir() {
+				return absSrc != absDir && strings.HasPrefix(srcinfo.Name(), "."), nil
+			}
+			return strings.HasSuffix(srcinfo.Name(), ".bak"), nil
+		},
+	})
+}
+
+// IsEmptyDir reports whether dir has no entries. A missing dir is empty
+func IsEmptyDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	return len(entries) == 0, err
+}
+
+func UnifiedDiff(path string, original string, content string) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(original),
+		B:        splitLines(content),
+		FromFile: "a/" + filepath.ToSlash(path),
+		ToFile:   "b/" + filepath.ToSlash(path),
+		Context:  3,
+	})
+}
+
 func ReadHCLFile(path string) (*hclwrite.File, error) {
 	src, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	return ParseHCL(src, path)
+}
+
+func ParseHCL(src []byte, path string) (*hclwrite.File, error) {
 	file, diagnostics := hclwrite.ParseConfig(src, path, hcl.InitialPos)
 	if err := multierr.Combine(diagnostics.Errs()...); err != nil {
 		return nil, err
@@ -111,6 +166,15 @@ func Exists(path string) bool {
 	return !errors.Is(err, os.ErrNotExist)
 }
 
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += "\n\\ No newline at end of file\n"
+	return lines
+}
+
 func createFile(path string, textContent string) error {
 	log.Print("[INFO] Creating file ", path)
 	return ioutil.WriteFile(path, []byte(textContent), 0644)
@@ -8,34 +8,27 @@ import (
 	"strings"
 )
 
+// Version is a semantic version. Missing parts are 0
 type Version struct {
-	Major int
-	Minor int
-	Patch int
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
 }
 
 const provider_pattern string = "(?m)provider (.+?) (.+?)$"
-const provider_version_pattern string = `v(\d+).(\d+)\.?(\d+)?`
-const string_version_pattern string = `(\d+).(\d+)\.?(\d+)?`
+const provider_version_pattern string = `v(\d+)\.?(\d+)?\.?(\d+)?(?:-([0-9A-Za-z.-]+))?`
+const string_version_pattern string = `(\d+)\.?(\d+)?\.?(\d+)?(?:-([0-9A-Za-z.-]+))?`
 const terraform_version_pattern string = `Terraform v(\d+).(\d+)\.(\d+)`
 
-var SUPPORTED_PROVIDERS = []string{"azurerm"}
-
 func ParseTerraformOutput(terraformVersionOutput *string) (map[string]Version, error) {
 	providerVersions := getProvidersVersions(terraformVersionOutput)
 	providerVersions["terraform"] = getTerraformVersion(terraformVersionOutput)
 	return providerVersions, nil
 }
 
-func IsSupportedResource(resourceType string) bool {
-	switch getProviderByResource(resourceType) {
-	case "azurerm":
-		return true
-	default:
-		return false
-	}
-}
-
+// ExtractProviderNameFromResourceType returns the local name of the provider of
+// a resource type, its prefix: aws for aws_s3_bucket, google for google_storage_bucket
 func ExtractProviderNameFromResourceType(resourceType string) (string, error) {
 	s := strings.SplitN(resourceType, "_", 2)
 	if len(s) < 2 {
@@ -72,41 +65,46 @@ func getProvidersVersions(terraformVersionOutput *string) map[string]Version {
 
 func parseVersion(pattern string, s *string) Version {
 	versionRegex := regexp.MustCompile(pattern)
-	versionMatch := versionRegex.FindStringSubmatch(*s)[1:]
+	versionMatch := versionRegex.FindStringSubmatch(*s)
+	if versionMatch == nil {
+		return Version{}
+	}
+	versionMatch = versionMatch[1:]
 
 	versionFrags := make([]int, 3)
 	for i := range versionFrags {
 		versionFrags[i] = getVersionPart(versionMatch, i)
 	}
 
-	return Version{
+	version := Version{
 		Major: versionFrags[0],
 		Minor: versionFrags[1],
 		Patch: versionFrags[2],
 	}
+	if len(versionMatch) > 3 {
+		version.Prerelease = versionMatch[3]
+	}
+
+	return version
 }
 
 func getVersionPart(parts []string, versionPart int) int {
 	if len(parts)-1 < int(versionPart) {
-		return -1
+		return 0
 	}
 
 	version, err := strconv.Atoi(parts[versionPart])
 	if err != nil {
-		return -1
+		return 0
 	}
 
 	return version
 }
 
-func getProviderByResource(resourceType string) string {
-	if strings.HasPrefix(resourceType, "aws_") {
-		return "aws"
-	} else if strings.HasPrefix(resourceType, "google_") {
-		return "gcp"
-	} else if strings.HasPrefix(resourceType, "azurerm_") || strings.HasPrefix(resourceType, "azurestack_") {
-		return "azurerm"
+func (v Version) String() string {
+	version := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		version += "-" + v.Prerelease
 	}
-
-	return ""
+	return version
 }
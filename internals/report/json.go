@@ -0,0 +1,126 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/clearbank/terrapolicy/policies"
+)
+
+// JSON_REPORT_VERSION must be increased on any breaking change of the document layout
+const JSON_REPORT_VERSION = 1
+
+type JsonReporter struct{}
+
+type jsonReport struct {
+	ReportVersion      int            `json:"report_version"`
+	TerrapolicyVersion string         `json:"terrapolicy_version"`
+	FailOn             string         `json:"fail_on"`
+	Summary            map[string]int `json:"summary"`
+	Findings           []jsonFinding  `json:"findings"`
+	StaleWaivers       []jsonWaiver   `json:"stale_waivers"`
+}
+
+type jsonFinding struct {
+	Policy       jsonPolicy        `json:"policy"`
+	Outcome      string            `json:"outcome"`
+	Blocking     bool              `json:"blocking"`
+	Reason       string            `json:"reason"`
+	File         string            `json:"file"`
+	Resource     jsonResource      `json:"resource"`
+	Remediations []jsonRemediation `json:"remediations"`
+}
+
+type jsonPolicy struct {
+	Id                  string            `json:"id"`
+	Name                string            `json:"name"`
+	Description         string            `json:"description"`
+	RemediationGuidance string            `json:"remediation_guidance"`
+	Labels              map[string]string `json:"labels"`
+	Type                string            `json:"type"`
+	Severity            string            `json:"severity"`
+	Params              interface{}       `json:"params"`
+}
+
+type jsonResource struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type jsonWaiver struct {
+	Policy        string `json:"policy"`
+	Resource      string `json:"resource"`
+	File          string `json:"file"`
+	Justification string `json:"justification"`
+	Approver      string `json:"approver"`
+	Expires       string `json:"expires"`
+}
+
+type jsonRemediation struct {
+	Attribute string      `json:"attribute"`
+	Value     interface{} `json:"value"`
+}
+
+func (r *JsonReporter) Write(w io.Writer, payload ReportPayload) error {
+	report := jsonReport{
+		ReportVersion:      JSON_REPORT_VERSION,
+		TerrapolicyVersion: payload.Version,
+		FailOn:             payload.FailOn.OrLowest().String(),
+		Summary: map[string]int{
+			policies.OUTCOME_SUCCESS.String():    0,
+			policies.OUTCOME_FAIL.String():       0,
+			policies.OUTCOME_REMEDIATE.String():  0,
+			policies.OUTCOME_SUPPRESSED.String(): 0,
+			policies.OUTCOME_WAIVED.String():     0,
+			policies.OUTCOME_WARN.String():       0,
+		},
+		Findings:     []jsonFinding{},
+		StaleWaivers: []jsonWaiver{},
+	}
+
+	for _, waiver := range payload.StaleWaivers {
+		report.StaleWaivers = append(report.StaleWaivers, jsonWaiver{
+			Policy:        waiver.Policy,
+			Resource:      waiver.Resource,
+			File:          waiver.File,
+			Justification: waiver.Justification,
+			Approver:      waiver.Approver,
+			Expires:       waiver.Expires.String(),
+		})
+	}
+
+	for _, finding := range payload.Findings {
+		result := finding.Result
+		remediations := []jsonRemediation{}
+		for _, remediation := range result.Remediations {
+			remediations = append(remediations, jsonRemediation{
+				Attribute: remediation.Attribute,
+				Value:     normalize(remediation.Value),
+			})
+		}
+
+		report.Summary[result.Outcome.String()]++
+		report.Findings = append(report.Findings, jsonFinding{
+			Policy: jsonPolicy{
+				Id:                  finding.Policy.Id,
+				Name:                finding.Policy.Name,
+				Description:         finding.Policy.Description,
+				RemediationGuidance: finding.Policy.RemediationGuidance,
+				Labels:              finding.Policy.Labels,
+				Type:                finding.Policy.Type,
+				Severity:            finding.Policy.GetSeverity().String(),
+				Params:              normalize(finding.Policy.Params),
+			},
+			Outcome:      result.Outcome.String(),
+			Blocking:     isBlocking(finding, payload.FailOn),
+			Reason:       result.Reason,
+			File:         finding.FilePath,
+			Resource:     jsonResource{Type: result.ResourceType, Name: result.ResourceName},
+			Remediations: remediations,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,167 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/clearbank/terrapolicy/internals/utils"
+	"github.com/clearbank/terrapolicy/policies"
+)
+
+type JunitReporter struct{}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (r *JunitReporter) Write(w io.Writer, payload ReportPayload) error {
+	findingsByRef := make(map[string][]policies.Finding)
+	for _, finding := range payload.Findings {
+		findingsByRef[finding.PolicyRef] = append(findingsByRef[finding.PolicyRef], finding)
+	}
+
+	report := junitTestSuites{Name: TOOL_NAME}
+	appendSuite := func(ref string, block policies.PolicyBlock) {
+		suite := newTestSuite(ref, block, findingsByRef[ref], payload.FailOn)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+		delete(findingsByRef, ref)
+	}
+	appendSuites := func(section string, blocks []policies.PolicyBlock) {
+		for i, block := range blocks {
+			appendSuite(policies.Ref(section, i), block)
+		}
+	}
+
+	appendSuites(policies.SECTION_PROVIDERS, payload.Policy.Providers)
+	appendSuites(policies.SECTION_RESOURCES, payload.Policy.Resources)
+
+	// findings of no policy block of the policy file, e.g. on invalid blocks
+	for _, finding := range payload.Findings {
+		if _, ok := findingsByRef[finding.PolicyRef]; ok {
+			appendSuite(finding.PolicyRef, finding.Policy)
+		}
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func newTestSuite(ref string, block policies.PolicyBlock, findings []policies.Finding, failOn policies.Severity) junitTestSuite {
+	suite := junitTestSuite{
+		Name: fmt.Sprintf("%v %v", ref, block.Type),
+		Properties: []junitProperty{
+			{Name: "type", Value: block.Type},
+			{Name: "severity", Value: block.GetSeverity().String()},
+		},
+		Cases: []junitTestCase{},
+	}
+
+	if block.Id != "" {
+		suite.Name = block.Id
+		suite.Properties = append(suite.Properties, junitProperty{Name: "id", Value: block.Id})
+	}
+	if block.Name != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "name", Value: block.Name})
+	}
+
+	for _, label := range utils.SortedKeys(block.Labels) {
+		suite.Properties = append(suite.Properties, junitProperty{
+			Name:  "labels." + label,
+			Value: block.Labels[label],
+		})
+	}
+
+	for _, name := range utils.SortedKeys(block.Params) {
+		suite.Properties = append(suite.Properties, junitProperty{
+			Name:  "params." + name,
+			Value: fmt.Sprintf("%v", block.Params[name]),
+		})
+	}
+
+	for _, finding := range findings {
+		result := finding.Result
+		testCase := junitTestCase{Name: result.Address(), Classname: finding.FilePath}
+		if testCase.Classname == "" {
+			testCase.Classname = result.ResourceType
+		}
+
+		switch {
+		case result.Outcome == policies.OUTCOME_FAIL && !isBlocking(finding, failOn):
+			testCase.SystemOut = fmt.Sprintf("non blocking %v failure: %v\n", block.GetSeverity(), result.Reason)
+		case result.Outcome == policies.OUTCOME_FAIL:
+			suite.Failures++
+			testCase.Failure = &junitFailure{
+				Message: result.Reason,
+				Type:    result.Outcome.String(),
+				Text:    strings.TrimSpace(result.Reason + "\n" + block.RemediationGuidance),
+			}
+		case result.Outcome == policies.OUTCOME_SUPPRESSED:
+			suite.Skipped++
+			testCase.Skipped = &junitSkipped{Message: "suppressed: " + result.Reason}
+		case result.Outcome == policies.OUTCOME_WAIVED:
+			suite.Skipped++
+			testCase.Skipped = &junitSkipped{Message: result.Reason}
+		case result.Outcome == policies.OUTCOME_WARN:
+			testCase.SystemOut = fmt.Sprintf("warning: %v\n", result.Reason)
+		case result.Outcome == policies.OUTCOME_REMEDIATE:
+			for _, remediation := range result.Remediations {
+				testCase.SystemOut += fmt.Sprintf("remediated: %v set to %v\n", remediation.Attribute, remediation.Value)
+			}
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	return suite
+}
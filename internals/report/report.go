@@ -0,0 +1,106 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/clearbank/terrapolicy/policies"
+)
+
+const TOOL_NAME = "terrapolicy"
+
+type ReportPayload struct {
+	Policy       policies.Policy
+	Findings     []policies.Finding
+	StaleWaivers []policies.Waiver
+	Version      string
+	FailOn       policies.Severity
+}
+
+type Reporter interface {
+	Write(w io.Writer, payload ReportPayload) error
+}
+
+var REPORTERS = map[string]Reporter{
+	"json":  &JsonReporter{},
+	"sarif": &SarifReporter{},
+	"junit": &JunitReporter{},
+}
+
+func IsSupportedFormat(format string) bool {
+	_, ok := REPORTERS[format]
+	return ok
+}
+
+// Write renders the payload with the reporter registered for format, to path
+// or to stdout when path is empty
+func Write(format string, path string, payload ReportPayload) error {
+	reporter, ok := REPORTERS[format]
+	if !ok {
+		log.Printf("[ERROR] cannot locate reporter for: %v", format)
+		return errors.New("unknown_report_format")
+	}
+
+	var buffer bytes.Buffer
+	if err := reporter.Write(&buffer, payload); err != nil {
+		log.Printf("[ERROR] %v", err)
+		return errors.New("report_write")
+	}
+
+	var err error
+	if path == "" {
+		_, err = os.Stdout.Write(buffer.Bytes())
+	} else {
+		err = os.WriteFile(path, buffer.Bytes(), 0644)
+	}
+
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return errors.New("report_write")
+	}
+
+	log.Printf("[INFO] %v report written to %v", format, destination(path))
+	return nil
+}
+
+// normalize converts the nested maps produced by the yaml decoder into
+// string keyed maps so they can be encoded by the standard encoders
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			m[fmt.Sprintf("%v", k)] = normalize(v)
+		}
+		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			m[k] = normalize(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(value))
+		for i, v := range value {
+			s[i] = normalize(v)
+		}
+		return s
+	default:
+		return value
+	}
+}
+
+func isBlocking(finding policies.Finding, failOn policies.Severity) bool {
+	return finding.Result.Outcome == policies.OUTCOME_FAIL && finding.Policy.GetSeverity().IsBlocking(failOn)
+}
+
+func destination(path string) string {
+	if path == "" {
+		return "stdout"
+	}
+	return path
+}
@@ -0,0 +1,215 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/clearbank/terrapolicy/policies"
+
+	"github.com/hashicorp/hcl/v2"
+	. "github.com/onsi/gomega"
+)
+
+var testPolicy = policies.Policy{
+	Resources: []policies.PolicyBlock{testFindings[0].Policy, testFindings[1].Policy},
+}
+
+var testFindings = []policies.Finding{
+	{
+		PolicyRef: "resources[0]",
+		Policy: policies.PolicyBlock{
+			Id:                  "storage-tls",
+			Name:                "Storage accounts enforce TLS 1.2",
+			RemediationGuidance: "Set min_tls_version to TLS1_2",
+			Labels:              map[string]string{"team": "platform"},
+			Type:                "attributes_policy",
+			Params: map[string]interface{}{
+				"resource":  "azurerm_storage_account",
+				"attribute": "min_tls_version",
+				"value":     "TLS1_2",
+				"strategy":  "force_set",
+				"nested":    map[interface{}]interface{}{"key": "value"},
+			},
+		},
+		FilePath: "main.tf",
+		Result: policies.PolicyResult{
+			Outcome:      policies.OUTCOME_REMEDIATE,
+			ResourceType: "azurerm_storage_account",
+			ResourceName: "test",
+			Range:        testRange(1, 1, 7, 2),
+			Remediations: []policies.Remediation{{
+				Attribute: "min_tls_version",
+				Value:     "TLS1_2",
+				Range:     testRange(7, 1, 7, 1),
+				Text:      "  min_tls_version = \"TLS1_2\"\n",
+			}},
+		},
+	},
+	{
+		PolicyRef: "resources[1]",
+		Policy: policies.PolicyBlock{
+			Type:     "attributes_policy",
+			Severity: policies.SEVERITY_MEDIUM,
+			Params:   map[string]interface{}{"strategy": "fail_if_missing"},
+		},
+		FilePath: "main.tf",
+		Result: policies.PolicyResult{
+			Outcome:      policies.OUTCOME_FAIL,
+			Reason:       "Attribute non conformant",
+			ResourceType: "azurerm_application_insights",
+			ResourceName: "test",
+			Range:        testRange(9, 1, 14, 2),
+		},
+	},
+}
+
+func TestJsonReporter(t *testing.T) {
+	g := NewWithT(t)
+
+	var buffer bytes.Buffer
+	err := REPORTERS["json"].Write(&buffer, ReportPayload{Findings: testFindings, Version: "test"})
+	g.Expect(err).To(BeNil())
+
+	var report map[string]interface{}
+	g.Expect(json.Unmarshal(buffer.Bytes(), &report)).To(Succeed())
+
+	g.Expect(report["report_version"]).To(BeEquivalentTo(JSON_REPORT_VERSION))
+	g.Expect(report["summary"]).To(Equal(map[string]interface{}{"success": 0.0, "fail": 1.0, "remediate": 1.0, "suppressed": 0.0, "waived": 0.0, "warn": 0.0}))
+
+	findings := report["findings"].([]interface{})
+	g.Expect(findings).To(HaveLen(2))
+
+	remediated := findings[0].(map[string]interface{})
+	g.Expect(remediated["outcome"]).To(Equal("remediate"))
+	g.Expect(remediated["policy"].(map[string]interface{})["id"]).To(Equal("storage-tls"))
+	g.Expect(remediated["file"]).To(Equal("main.tf"))
+	g.Expect(remediated["resource"]).To(Equal(map[string]interface{}{"type": "azurerm_storage_account", "name": "test"}))
+	g.Expect(remediated["remediations"]).To(Equal([]interface{}{
+		map[string]interface{}{"attribute": "min_tls_version", "value": "TLS1_2"},
+	}))
+
+	failed := findings[1].(map[string]interface{})
+	g.Expect(failed["outcome"]).To(Equal("fail"))
+	g.Expect(failed["blocking"]).To(BeTrue())
+	g.Expect(failed["policy"].(map[string]interface{})["severity"]).To(Equal("medium"))
+	g.Expect(failed["reason"]).To(Equal("Attribute non conformant"))
+}
+
+func TestSarifReporter(t *testing.T) {
+	g := NewWithT(t)
+
+	var buffer bytes.Buffer
+	err := REPORTERS["sarif"].Write(&buffer, ReportPayload{Findings: testFindings, Version: "test"})
+	g.Expect(err).To(BeNil())
+
+	var log sarifLog
+	g.Expect(json.Unmarshal(buffer.Bytes(), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal(SARIF_VERSION))
+	g.Expect(log.Runs).To(HaveLen(1))
+
+	run := log.Runs[0]
+	g.Expect(run.Tool.Driver.Rules).To(HaveLen(2))
+	g.Expect(run.Tool.Driver.Rules[0].Id).To(Equal("storage-tls"))
+	g.Expect(run.Tool.Driver.Rules[0].ShortDescription.Text).To(Equal("Storage accounts enforce TLS 1.2"))
+	g.Expect(run.Tool.Driver.Rules[0].Help.Text).To(Equal("Set min_tls_version to TLS1_2"))
+	g.Expect(run.Tool.Driver.Rules[1].Id).To(Equal("attributes_policy"))
+	g.Expect(run.Results).To(HaveLen(2))
+	g.Expect(run.Results[0].RuleId).To(Equal("storage-tls"))
+
+	remediated := run.Results[0]
+	g.Expect(remediated.Level).To(Equal("warning"))
+	g.Expect(remediated.Locations[0].PhysicalLocation.ArtifactLocation.Uri).To(Equal("main.tf"))
+	g.Expect(remediated.Fixes).To(HaveLen(1))
+	g.Expect(remediated.Fixes[0].ArtifactChanges[0].Replacements[0]).To(Equal(sarifReplacement{
+		DeletedRegion:   sarifRegion{StartLine: 7, StartColumn: 1, EndLine: 7, EndColumn: 1},
+		InsertedContent: sarifMessage{Text: "  min_tls_version = \"TLS1_2\"\n"},
+	}))
+
+	failed := run.Results[1]
+	g.Expect(failed.Level).To(Equal("warning"))
+	g.Expect(*failed.Locations[0].PhysicalLocation.Region).To(Equal(sarifRegion{StartLine: 9, StartColumn: 1, EndLine: 14, EndColumn: 2}))
+	g.Expect(failed.Fixes).To(BeEmpty())
+}
+
+func TestJunitReporter(t *testing.T) {
+	g := NewWithT(t)
+
+	var buffer bytes.Buffer
+	err := REPORTERS["junit"].Write(&buffer, ReportPayload{Policy: testPolicy, Findings: testFindings})
+	g.Expect(err).To(BeNil())
+
+	var report junitTestSuites
+	g.Expect(xml.Unmarshal(buffer.Bytes(), &report)).To(Succeed())
+	g.Expect(report.Tests).To(Equal(2))
+	g.Expect(report.Failures).To(Equal(1))
+	g.Expect(report.Suites).To(HaveLen(2))
+
+	remediated := report.Suites[0]
+	g.Expect(remediated.Name).To(Equal("storage-tls"))
+	g.Expect(remediated.Properties).To(ContainElement(junitProperty{Name: "labels.team", Value: "platform"}))
+	g.Expect(remediated.Failures).To(Equal(0))
+	g.Expect(remediated.Cases).To(HaveLen(1))
+	g.Expect(remediated.Cases[0].Classname).To(Equal("main.tf"))
+	g.Expect(remediated.Cases[0].Name).To(Equal("azurerm_storage_account.test"))
+	g.Expect(remediated.Cases[0].SystemOut).To(ContainSubstring("min_tls_version set to TLS1_2"))
+
+	failed := report.Suites[1]
+	g.Expect(failed.Name).To(Equal("resources[1] attributes_policy"))
+	g.Expect(failed.Failures).To(Equal(1))
+	g.Expect(failed.Cases[0].Failure.Message).To(Equal("Attribute non conformant"))
+
+	buffer.Reset()
+	err = REPORTERS["junit"].Write(&buffer, ReportPayload{Policy: testPolicy, Findings: testFindings, FailOn: policies.SEVERITY_HIGH})
+	g.Expect(err).To(BeNil())
+
+	report = junitTestSuites{}
+	g.Expect(xml.Unmarshal(buffer.Bytes(), &report)).To(Succeed())
+
+	nonBlocking := report.Suites[1]
+	g.Expect(nonBlocking.Failures).To(Equal(0))
+	g.Expect(nonBlocking.Cases[0].Failure).To(BeNil())
+	g.Expect(nonBlocking.Cases[0].SystemOut).To(ContainSubstring("non blocking medium failure"))
+
+	// a finding of no policy block of the policy file
+	invalid := policies.Finding{
+		PolicyRef: "invalid_block",
+		Policy:    policies.PolicyBlock{Id: "invalid_block", Type: "invalid_block"},
+		FilePath:  "main.tf",
+		Result:    policies.PolicyResult{Outcome: policies.OUTCOME_FAIL, Reason: "resource block requires a type and a name label", ResourceType: "azurerm_storage_account"},
+	}
+
+	buffer.Reset()
+	err = REPORTERS["junit"].Write(&buffer, ReportPayload{Policy: testPolicy, Findings: append(testFindings[:2:2], invalid)})
+	g.Expect(err).To(BeNil())
+
+	report = junitTestSuites{}
+	g.Expect(xml.Unmarshal(buffer.Bytes(), &report)).To(Succeed())
+	g.Expect(report.Suites).To(HaveLen(3))
+	g.Expect(report.Suites[2].Name).To(Equal("invalid_block"))
+	g.Expect(report.Suites[2].Failures).To(Equal(1))
+
+	// a finding a policy could not evaluate
+	warned := testFindings[1]
+	warned.Result.Outcome = policies.OUTCOME_WARN
+	warned.Result.Reason = "Cannot verify the hash of linux_amd64"
+
+	buffer.Reset()
+	err = REPORTERS["junit"].Write(&buffer, ReportPayload{Policy: testPolicy, Findings: []policies.Finding{warned}})
+	g.Expect(err).To(BeNil())
+
+	report = junitTestSuites{}
+	g.Expect(xml.Unmarshal(buffer.Bytes(), &report)).To(Succeed())
+	g.Expect(report.Failures).To(Equal(0))
+	g.Expect(report.Suites[1].Cases[0].Failure).To(BeNil())
+	g.Expect(report.Suites[1].Cases[0].SystemOut).To(Equal("warning: Cannot verify the hash of linux_amd64\n"))
+}
+
+func testRange(startLine, startColumn, endLine, endColumn int) hcl.Range {
+	return hcl.Range{
+		Filename: "main.tf",
+		Start:    hcl.Pos{Line: startLine, Column: startColumn},
+		End:      hcl.Pos{Line: endLine, Column: endColumn},
+	}
+}
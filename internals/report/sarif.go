@@ -0,0 +1,272 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/clearbank/terrapolicy/policies"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+const (
+	SARIF_VERSION  = "2.1.0"
+	SARIF_SCHEMA   = "https://json.schemastore.org/sarif-2.1.0.json"
+	SARIF_TOOL_URI = "https://github.com/clearbank/terrapolicy"
+)
+
+const (
+	sarif_level_remediate = "warning"
+	sarif_level_warn      = "warning"
+)
+
+var sarifLevels = map[policies.Severity]string{
+	policies.SEVERITY_INFO:     "note",
+	policies.SEVERITY_LOW:      "note",
+	policies.SEVERITY_MEDIUM:   "warning",
+	policies.SEVERITY_HIGH:     "error",
+	policies.SEVERITY_CRITICAL: "error",
+}
+
+type SarifReporter struct{}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id               string               `json:"id"`
+	Name             string               `json:"name,omitempty"`
+	ShortDescription sarifMessage         `json:"shortDescription"`
+	FullDescription  *sarifMessage        `json:"fullDescription,omitempty"`
+	Help             *sarifMessage        `json:"help,omitempty"`
+	Properties       *sarifRuleProperties `json:"properties,omitempty"`
+}
+
+type sarifRuleProperties struct {
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type sarifResult struct {
+	RuleId       string             `json:"ruleId"`
+	RuleIndex    int                `json:"ruleIndex"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations,omitempty"`
+	Fixes        []sarifFix         `json:"fixes,omitempty"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+func (r *SarifReporter) Write(w io.Writer, payload ReportPayload) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           TOOL_NAME,
+			Version:        payload.Version,
+			InformationUri: SARIF_TOOL_URI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	ruleIndexes := make(map[string]int)
+	for _, finding := range payload.Findings {
+		result := finding.Result
+
+		var level string
+		switch result.Outcome {
+		case policies.OUTCOME_FAIL, policies.OUTCOME_SUPPRESSED, policies.OUTCOME_WAIVED:
+			level = sarifLevels[finding.Policy.GetSeverity()]
+		case policies.OUTCOME_REMEDIATE:
+			level = sarif_level_remediate
+		case policies.OUTCOME_WARN:
+			level = sarif_level_warn
+		default:
+			continue
+		}
+
+		ruleId := ruleId(finding.Policy)
+		ruleIndex, ok := ruleIndexes[ruleId]
+		if !ok {
+			ruleIndex = len(run.Tool.Driver.Rules)
+			ruleIndexes[ruleId] = ruleIndex
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, newRule(ruleId, finding.Policy))
+		}
+
+		sarifResult := sarifResult{
+			RuleId:    ruleId,
+			RuleIndex: ruleIndex,
+			Level:     level,
+			Message:   sarifMessage{Text: message(finding)},
+		}
+
+		if finding.FilePath != "" {
+			sarifResult.Locations = []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{Uri: uri(finding.FilePath)},
+				Region:           region(result.Range),
+			}}}
+		}
+
+		switch result.Outcome {
+		case policies.OUTCOME_SUPPRESSED:
+			sarifResult.Suppressions = []sarifSuppression{{Kind: "inSource", Justification: result.Reason}}
+		case policies.OUTCOME_WAIVED:
+			sarifResult.Suppressions = []sarifSuppression{{Kind: "external", Justification: result.Reason}}
+		}
+
+		for _, remediation := range result.Remediations {
+			if remediation.Range.Filename == "" {
+				continue
+			}
+			sarifResult.Fixes = append(sarifResult.Fixes, sarifFix{
+				Description: sarifMessage{Text: fmt.Sprintf("Set %v to %v", remediation.Attribute, remediation.Value)},
+				ArtifactChanges: []sarifArtifactChange{{
+					ArtifactLocation: sarifArtifactLocation{Uri: uri(finding.FilePath)},
+					Replacements: []sarifReplacement{{
+						DeletedRegion:   *region(remediation.Range),
+						InsertedContent: sarifMessage{Text: remediation.Text},
+					}},
+				}},
+			})
+		}
+
+		run.Results = append(run.Results, sarifResult)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Schema:  SARIF_SCHEMA,
+		Version: SARIF_VERSION,
+		Runs:    []sarifRun{run},
+	})
+}
+
+func ruleId(block policies.PolicyBlock) string {
+	if block.Id != "" {
+		return block.Id
+	}
+	return block.Type
+}
+
+func newRule(id string, block policies.PolicyBlock) sarifRule {
+	rule := sarifRule{
+		Id:               id,
+		Name:             block.Name,
+		ShortDescription: sarifMessage{Text: id},
+		Properties:       &sarifRuleProperties{Type: block.Type, Labels: block.Labels},
+	}
+
+	if block.Name != "" {
+		rule.ShortDescription = sarifMessage{Text: block.Name}
+	}
+	if block.Description != "" {
+		rule.FullDescription = &sarifMessage{Text: block.Description}
+	}
+	if block.RemediationGuidance != "" {
+		rule.Help = &sarifMessage{Text: block.RemediationGuidance}
+	}
+
+	return rule
+}
+
+func message(finding policies.Finding) string {
+	result, id := finding.Result, ruleId(finding.Policy)
+	switch result.Outcome {
+	case policies.OUTCOME_REMEDIATE:
+		return fmt.Sprintf("%v remediated by %v", result.Address(), id)
+	case policies.OUTCOME_SUPPRESSED:
+		return fmt.Sprintf("%v suppressed %v: %v", result.Address(), id, result.Reason)
+	case policies.OUTCOME_WAIVED:
+		return fmt.Sprintf("%v failed %v, %v", result.Address(), id, result.Reason)
+	case policies.OUTCOME_WARN:
+		return fmt.Sprintf("%v not evaluated by %v: %v", result.Address(), id, result.Reason)
+	default:
+		return fmt.Sprintf("%v failed %v: %v", result.Address(), id, result.Reason)
+	}
+}
+
+func region(r hcl.Range) *sarifRegion {
+	if r.Start.Line == 0 {
+		return nil
+	}
+	return &sarifRegion{
+		StartLine:   r.Start.Line,
+		StartColumn: r.Start.Column,
+		EndLine:     r.End.Line,
+		EndColumn:   r.End.Column,
+	}
+}
+
+func uri(path string) string {
+	path = filepath.ToSlash(path)
+	if filepath.IsAbs(path) {
+		return "file://" + path
+	}
+	return strings.TrimPrefix(path, "./")
+}
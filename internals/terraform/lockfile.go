@@ -0,0 +1,81 @@
+package terraform
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+const LOCK_FILE_NAME = ".terraform.lock.hcl"
+
+// LockedProvider is a provider selected by terraform init, as recorded in the lock file
+type LockedProvider struct {
+	// Address is the fully qualified source address, e.g. registry.terraform.io/hashicorp/azurerm
+	Address string `hcl:"address,label"`
+	// Version is the exact selected version
+	Version     string   `hcl:"version"`
+	Constraints string   `hcl:"constraints,optional"`
+	Hashes      []string `hcl:"hashes,optional"`
+}
+
+type lockFile struct {
+	Providers []LockedProvider `hcl:"provider,block"`
+	Remain    hcl.Body         `hcl:",remain"`
+}
+
+// ReadLockFile returns the providers of the lock file of the root module, or
+// nothing if the root module has no lock file. A lock file without providers
+// returns an empty, non-nil slice
+func ReadLockFile(dir string) ([]LockedProvider, error) {
+	lockPath := filepath.Join(dir, LOCK_FILE_NAME)
+	src, err := os.ReadFile(lockPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lock lockFile
+	if err := hclsimple.Decode(lockPath, src, nil, &lock); err != nil {
+		return nil, err
+	}
+
+	if lock.Providers == nil {
+		return []LockedProvider{}, nil
+	}
+	return lock.Providers, nil
+}
+
+// Name returns the type of the provider, the last part of its address
+func (p LockedProvider) Name() string {
+	return path.Base(p.Address)
+}
+
+// lockFileRanges locates the provider blocks of the lock file of the root module,
+// by address
+func lockFileRanges(dir string) (map[string]hcl.Range, error) {
+	ranges := make(map[string]hcl.Range)
+	lockPath := filepath.Join(dir, LOCK_FILE_NAME)
+	if _, err := os.Stat(lockPath); os.IsNotExist(err) {
+		return ranges, nil
+	}
+
+	f, diags := hclparse.NewParser().ParseHCLFile(lockPath)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	for _, block := range f.Body.(*hclsyntax.Body).Blocks {
+		if block.Type == "provider" && len(block.Labels) == 1 {
+			ranges[block.Labels[0]] = block.Range()
+		}
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,46 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestReadLockFile(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	locked, err := ReadLockFile(dir)
+	g.Expect(err).To(BeNil())
+	g.Expect(locked).To(BeNil())
+
+	// an existing lock file without providers is not a missing one
+	g.Expect(os.WriteFile(filepath.Join(dir, LOCK_FILE_NAME), []byte("# This file is maintained automatically by \"terraform init\".\n"), 0644)).To(Succeed())
+	locked, err = ReadLockFile(dir)
+	g.Expect(err).To(BeNil())
+	g.Expect(locked).NotTo(BeNil())
+	g.Expect(locked).To(BeEmpty())
+
+	g.Expect(os.WriteFile(filepath.Join(dir, LOCK_FILE_NAME), []byte(`
+# This file is maintained automatically by "terraform init".
+provider "registry.terraform.io/hashicorp/azurerm" {
+  version     = "3.44.1"
+  constraints = "~> 3.44"
+  hashes = [
+    "h1:qLt+AOf/EeKwAq4Ph1OAT0+H5Ld/fIefr/9uqsEX9sY=",
+    "zh:047e22b2e02d57fb1d945d52c4bd062f50a657865b6a8d21f96ba55ef8a474e5",
+  ]
+}
+`), 0644)).To(Succeed())
+
+	locked, err = ReadLockFile(dir)
+	g.Expect(err).To(BeNil())
+	g.Expect(locked).To(HaveLen(1))
+	g.Expect(locked[0].Address).To(Equal("registry.terraform.io/hashicorp/azurerm"))
+	g.Expect(locked[0].Name()).To(Equal("azurerm"))
+	g.Expect(locked[0].Version).To(Equal("3.44.1"))
+	g.Expect(locked[0].Constraints).To(Equal("~> 3.44"))
+	g.Expect(locked[0].Hashes).To(HaveLen(2))
+}
@@ -0,0 +1,224 @@
+package terraform
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// ErrNotReleased is returned for a platform the provider version has no package for
+var ErrNotReleased = errors.New("not released")
+
+var errNotFound = errors.New("not found")
+
+const registry_timeout = 30 * time.Second
+
+// PackageHashes looks up the hash of the package of a provider version on a
+// platform, one of those terraform init checks the package against
+type PackageHashes struct {
+	// RootDir is the root module, whose installed packages are hashed
+	RootDir string
+	// Registry enables the lookup of the packages not installed in the registry of the provider
+	Registry bool
+	// Client queries the registries, defaults to a client with a timeout
+	Client *http.Client
+
+	hashes    map[string]string
+	providers map[string]*url.URL
+	shasums   map[string]map[string]string
+}
+
+type registryDownload struct {
+	Filename   string `json:"filename"`
+	ShasumsUrl string `json:"shasums_url"`
+}
+
+// Hash returns the h1: hash of the package installed by terraform init for the
+// platform, e.g. linux_amd64, in the root module or the plugin cache. Packages not
+// installed are looked up in the registry, if enabled: the zh: hash is the
+// checksum of the package the registry publishes in the SHA256SUMS of the release,
+// which terraform init checks when it downloads the package. No package is downloaded
+func (h *PackageHashes) Hash(provider LockedProvider, platform string) (string, error) {
+	key := strings.Join([]string{provider.Address, provider.Version, platform}, "/")
+	if hash, ok := h.hashes[key]; ok {
+		return hash, nil
+	}
+
+	hash, err := h.hash(provider, platform)
+	if err != nil {
+		return "", err
+	}
+
+	if h.hashes == nil {
+		h.hashes = make(map[string]string)
+	}
+	h.hashes[key] = hash
+	return hash, nil
+}
+
+func (h *PackageHashes) hash(provider LockedProvider, platform string) (string, error) {
+	for _, dir := range h.packageDirs(provider, platform) {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			log.Printf("[DEBUG] hashing package %v", dir)
+			resolved, err := filepath.EvalSymlinks(dir)
+			if err != nil {
+				return "", err
+			}
+			return dirhash.HashDir(resolved, "", dirhash.Hash1)
+		}
+	}
+
+	if !h.Registry {
+		return "", fmt.Errorf("the package of %v is not installed and the registry lookup is disabled", platform)
+	}
+	return h.registryHash(provider, platform)
+}
+
+// packageDirs returns the directories terraform init unpacks the package in
+func (h *PackageHashes) packageDirs(provider LockedProvider, platform string) []string {
+	rel := filepath.Join(filepath.FromSlash(provider.Address), provider.Version, platform)
+	dirs := []string{filepath.Join(h.RootDir, ".terraform", "providers", rel)}
+	if cacheDir := os.Getenv("TF_PLUGIN_CACHE_DIR"); cacheDir != "" {
+		dirs = append(dirs, filepath.Join(cacheDir, rel))
+	}
+	return dirs
+}
+
+func (h *PackageHashes) registryHash(provider LockedProvider, platform string) (string, error) {
+	parts := strings.Split(provider.Address, "/")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid provider address: %v", provider.Address)
+	}
+
+	goos, goarch, ok := strings.Cut(platform, "_")
+	if !ok {
+		return "", fmt.Errorf("invalid platform: %v", platform)
+	}
+
+	base, err := h.providersUrl(parts[0])
+	if err != nil {
+		return "", err
+	}
+
+	downloadUrl := base.JoinPath(parts[1], parts[2], provider.Version, "download", goos, goarch)
+	var download registryDownload
+	if err := h.getJson(downloadUrl.String(), &download); errors.Is(err, errNotFound) {
+		return "", fmt.Errorf("%v %v is %w for %v", provider.Address, provider.Version, ErrNotReleased, platform)
+	} else if err != nil {
+		return "", err
+	}
+
+	shasumsUrl, err := downloadUrl.Parse(download.ShasumsUrl)
+	if err != nil {
+		return "", err
+	}
+
+	shasums, err := h.getShasums(shasumsUrl.String())
+	if err != nil {
+		return "", err
+	}
+
+	shasum, ok := shasums[download.Filename]
+	if !ok {
+		return "", fmt.Errorf("%v is not listed in %v", download.Filename, shasumsUrl)
+	}
+	return "zh:" + shasum, nil
+}
+
+// providersUrl discovers the provider registry API of the host
+func (h *PackageHashes) providersUrl(host string) (*url.URL, error) {
+	if providersUrl, ok := h.providers[host]; ok {
+		return providersUrl, nil
+	}
+
+	discoveryUrl := &url.URL{Scheme: "https", Host: host, Path: "/.well-known/terraform.json"}
+
+	var services map[string]interface{}
+	if err := h.getJson(discoveryUrl.String(), &services); err != nil {
+		return nil, fmt.Errorf("cannot discover the services of %v: %v", host, err)
+	}
+
+	providers, ok := services["providers.v1"].(string)
+	if !ok {
+		return nil, fmt.Errorf("%v is not a provider registry", host)
+	}
+
+	providersUrl, err := discoveryUrl.Parse(providers)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.providers == nil {
+		h.providers = make(map[string]*url.URL)
+	}
+	h.providers[host] = providersUrl
+	return providersUrl, nil
+}
+
+// getShasums returns the checksums of a SHA256SUMS file, by file name
+func (h *PackageHashes) getShasums(url string) (map[string]string, error) {
+	if shasums, ok := h.shasums[url]; ok {
+		return shasums, nil
+	}
+
+	response, err := h.client().Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v returned %v", url, response.Status)
+	}
+
+	shasums := make(map[string]string)
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+			shasums[fields[1]] = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if h.shasums == nil {
+		h.shasums = make(map[string]map[string]string)
+	}
+	h.shasums[url] = shasums
+	return shasums, nil
+}
+
+func (h *PackageHashes) getJson(url string, v interface{}) error {
+	response, err := h.client().Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%v returned %w", url, errNotFound)
+	}
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%v returned %v", url, response.Status)
+	}
+
+	return json.NewDecoder(response.Body).Decode(v)
+}
+
+func (h *PackageHashes) client() *http.Client {
+	if h.Client == nil {
+		h.Client = &http.Client{Timeout: registry_timeout}
+	}
+	return h.Client
+}
@@ -0,0 +1,46 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+func TestPackageHashesInstalled(t *testing.T) {
+	g := NewWithT(t)
+
+	provider := LockedProvider{Address: "registry.terraform.io/hashicorp/random", Version: "3.4.3"}
+
+	cacheDir := t.TempDir()
+	t.Setenv("TF_PLUGIN_CACHE_DIR", cacheDir)
+	packageDir := filepath.Join(cacheDir, "registry.terraform.io", "hashicorp", "random", "3.4.3", "linux_amd64")
+	g.Expect(os.MkdirAll(packageDir, 0755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(packageDir, "terraform-provider-random"), []byte("random"), 0755)).To(Succeed())
+
+	expected, err := dirhash.HashDir(packageDir, "", dirhash.Hash1)
+	g.Expect(err).To(BeNil())
+
+	hashes := &PackageHashes{RootDir: t.TempDir()}
+	hash, err := hashes.Hash(provider, "linux_amd64")
+	g.Expect(err).To(BeNil())
+	g.Expect(hash).To(Equal(expected))
+	g.Expect(hash).To(HavePrefix("h1:"))
+
+	// hashed once per platform
+	g.Expect(os.RemoveAll(packageDir)).To(Succeed())
+	hash, err = hashes.Hash(provider, "linux_amd64")
+	g.Expect(err).To(BeNil())
+	g.Expect(hash).To(Equal(expected))
+
+	// not installed, without network
+	_, err = hashes.Hash(provider, "darwin_arm64")
+	g.Expect(err).To(MatchError("the package of darwin_arm64 is not installed and the registry lookup is disabled"))
+
+	hashes.Registry = true
+	g.Expect(hashes.client().Timeout).To(BeNumerically(">", 0))
+	_, err = hashes.Hash(LockedProvider{Address: "hashicorp/random", Version: "3.4.3"}, "darwin_arm64")
+	g.Expect(err).To(MatchError("invalid provider address: hashicorp/random"))
+}
@@ -0,0 +1,277 @@
+package terraform
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/clearbank/terrapolicy/internals/providers"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	DEFAULT_REGISTRY  = "registry.terraform.io"
+	DEFAULT_NAMESPACE = "hashicorp"
+)
+
+// RequiredProvider is an entry of the required_providers block of a module
+type RequiredProvider struct {
+	// Source is the fully qualified source address, e.g. registry.terraform.io/hashicorp/azurerm
+	Source  string
+	Version string
+}
+
+// ProviderRef identifies the provider configuration of a resource
+type ProviderRef struct {
+	// LocalName is the name of the provider in the module, e.g. azurerm
+	LocalName string
+	Alias     string
+	// Source is the fully qualified source address, e.g. registry.terraform.io/hashicorp/azurerm
+	Source string
+}
+
+// Type returns the type of the provider, the last part of its source address
+func (p ProviderRef) Type() string {
+	return path.Base(p.Source)
+}
+
+func (p ProviderRef) String() string {
+	if p.Alias != "" {
+		return fmt.Sprintf("%v.%v (%v)", p.LocalName, p.Alias, p.Source)
+	}
+	return fmt.Sprintf("%v (%v)", p.LocalName, p.Source)
+}
+
+// ModuleProviders holds the required providers of a module, by local name, and
+// the source addresses of the providers its configuration references
+type ModuleProviders struct {
+	Required   map[string]RequiredProvider
+	Referenced []string
+}
+
+// ReadModuleProviders returns the providers required by the terraform blocks of
+// the module in dir, and referenced by its provider blocks, resources and data sources
+func ReadModuleProviders(dir string) (*ModuleProviders, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	moduleProviders := &ModuleProviders{Required: make(map[string]RequiredProvider)}
+	parser := hclparse.NewParser()
+	var blocks hclsyntax.Blocks
+
+	for _, path := range paths {
+		f, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		blocks = append(blocks, f.Body.(*hclsyntax.Body).Blocks...)
+	}
+
+	for _, block := range blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+
+		for _, nested := range block.Body.Blocks {
+			if nested.Type != "required_providers" {
+				continue
+			}
+
+			for name, attribute := range nested.Body.Attributes {
+				required, err := parseRequiredProvider(name, attribute)
+				if err != nil {
+					return nil, err
+				}
+				moduleProviders.Required[name] = required
+			}
+		}
+	}
+
+	moduleProviders.Referenced = moduleProviders.referenced(blocks)
+	return moduleProviders, nil
+}
+
+// referenced returns the sorted source addresses of the required providers and
+// of the providers of the provider blocks, resources and data sources
+func (m *ModuleProviders) referenced(blocks hclsyntax.Blocks) []string {
+	sources := make(map[string]bool)
+	for _, required := range m.Required {
+		sources[required.Source] = true
+	}
+
+	for _, block := range blocks {
+		if len(block.Labels) == 0 {
+			continue
+		}
+
+		switch block.Type {
+		case "provider":
+			sources[m.source(block.Labels[0])] = true
+		case "resource", "data":
+			if attribute, ok := block.Body.Attributes["provider"]; ok {
+				traversal, diags := hcl.AbsTraversalForExpr(attribute.Expr)
+				if !diags.HasErrors() {
+					sources[m.source(traversal.RootName())] = true
+				}
+				continue
+			}
+			if name, err := providers.ExtractProviderNameFromResourceType(block.Labels[0]); err == nil {
+				sources[m.source(name)] = true
+			}
+		}
+	}
+
+	referenced := make([]string, 0, len(sources))
+	for source := range sources {
+		referenced = append(referenced, source)
+	}
+	sort.Strings(referenced)
+	return referenced
+}
+
+// ProviderRanges locates the providers of the root module in dir, by source
+// address: their entry of required_providers, or else their block of the lock
+// file. terraform itself is located by the required_version of the terraform block
+func ProviderRanges(dir string) (map[string]hcl.Range, error) {
+	ranges, err := lockFileRanges(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	for _, path := range paths {
+		f, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		for _, block := range f.Body.(*hclsyntax.Body).Blocks {
+			if block.Type != "terraform" {
+				continue
+			}
+
+			if attribute, ok := block.Body.Attributes["required_version"]; ok {
+				ranges["terraform"] = attribute.SrcRange
+			}
+
+			for _, nested := range block.Body.Blocks {
+				if nested.Type != "required_providers" {
+					continue
+				}
+
+				for name, attribute := range nested.Body.Attributes {
+					if required, err := parseRequiredProvider(name, attribute); err == nil {
+						ranges[required.Source] = attribute.SrcRange
+					}
+				}
+			}
+		}
+	}
+
+	return ranges, nil
+}
+
+// parseRequiredProvider parses `name = { source = "...", version = "..." }`, or
+// the legacy `name = "<version>"`
+func parseRequiredProvider(name string, attribute *hclsyntax.Attribute) (RequiredProvider, error) {
+	required := RequiredProvider{Source: NormalizeSource(name)}
+
+	// configuration_aliases are references, which cannot be evaluated without context
+	if object, ok := attribute.Expr.(*hclsyntax.ObjectConsExpr); ok {
+		for _, item := range object.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || key.Type() != cty.String {
+				continue
+			}
+
+			switch key.AsString() {
+			case "source":
+				value, err := stringValue(item.ValueExpr)
+				if err != nil {
+					return required, fmt.Errorf("invalid source of provider %v: %v", name, err)
+				}
+				required.Source = NormalizeSource(value)
+			case "version":
+				value, err := stringValue(item.ValueExpr)
+				if err != nil {
+					return required, fmt.Errorf("invalid version of provider %v: %v", name, err)
+				}
+				required.Version = value
+			}
+		}
+		return required, nil
+	}
+
+	value, err := stringValue(attribute.Expr)
+	if err != nil {
+		return required, fmt.Errorf("invalid requirement of provider %v: %v", name, err)
+	}
+	required.Version = value
+	return required, nil
+}
+
+func stringValue(expr hcl.Expression) (string, error) {
+	value, diags := expr.Value(nil)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	if value.Type() != cty.String || value.IsNull() {
+		return "", fmt.Errorf("not a string")
+	}
+	return value.AsString(), nil
+}
+
+// NormalizeSource returns the fully qualified source address of a provider:
+// azurerm and hashicorp/azurerm are registry.terraform.io/hashicorp/azurerm
+func NormalizeSource(source string) string {
+	parts := strings.Split(strings.ToLower(source), "/")
+	switch len(parts) {
+	case 1:
+		return strings.Join([]string{DEFAULT_REGISTRY, DEFAULT_NAMESPACE, parts[0]}, "/")
+	case 2:
+		return strings.Join([]string{DEFAULT_REGISTRY, parts[0], parts[1]}, "/")
+	default:
+		return strings.Join(parts, "/")
+	}
+}
+
+// Resolve returns the provider of a resource, set by its provider meta-argument
+// or implied by the prefix of its type
+func (m *ModuleProviders) Resolve(resource *hclwrite.Block) ProviderRef {
+	var ref ProviderRef
+
+	if attribute := resource.Body().GetAttribute("provider"); attribute != nil {
+		reference := strings.TrimSpace(string(attribute.Expr().BuildTokens(nil).Bytes()))
+		ref.LocalName, ref.Alias, _ = strings.Cut(reference, ".")
+	} else {
+		ref.LocalName, _ = providers.ExtractProviderNameFromResourceType(GetResourceType(resource))
+	}
+
+	ref.Source = m.source(ref.LocalName)
+	return ref
+}
+
+// source returns the source address of the provider with the local name
+func (m *ModuleProviders) source(localName string) string {
+	if m != nil {
+		if required, ok := m.Required[localName]; ok {
+			return required.Source
+		}
+	}
+	return NormalizeSource(localName)
+}
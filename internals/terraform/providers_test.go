@@ -0,0 +1,132 @@
+package terraform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	. "github.com/onsi/gomega"
+)
+
+const testProvidersFile = `
+terraform {
+  required_providers {
+    azurerm = {
+      source  = "mycorp/azurerm"
+      version = "~> 3.0"
+    }
+    azure = {
+      source                = "hashicorp/azurerm"
+      configuration_aliases = [azure.westeurope]
+    }
+    google = "~> 4.0"
+  }
+}
+`
+
+const testResourcesFile = `
+resource "azurerm_storage_account" "default" {}
+
+resource "azurerm_storage_account" "aliased" {
+  provider = azure.westeurope
+}
+
+resource "google_storage_bucket" "legacy" {}
+
+resource "aws_s3_bucket" "undeclared" {}
+`
+
+func TestResolveProvider(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(testProvidersFile), 0644)).To(Succeed())
+
+	providers, err := ReadModuleProviders(dir)
+	g.Expect(err).To(BeNil())
+	g.Expect(providers.Required).To(Equal(map[string]RequiredProvider{
+		"azurerm": {Source: "registry.terraform.io/mycorp/azurerm", Version: "~> 3.0"},
+		"azure":   {Source: "registry.terraform.io/hashicorp/azurerm"},
+		"google":  {Source: "registry.terraform.io/hashicorp/google", Version: "~> 4.0"},
+	}))
+
+	f, diags := hclwrite.ParseConfig([]byte(testResourcesFile), "main.tf", hcl.InitialPos)
+	g.Expect(diags.HasErrors()).To(BeFalse())
+
+	var refs []ProviderRef
+	for _, block := range f.Body().Blocks() {
+		refs = append(refs, providers.Resolve(block))
+	}
+
+	g.Expect(refs).To(Equal([]ProviderRef{
+		{LocalName: "azurerm", Source: "registry.terraform.io/mycorp/azurerm"},
+		{LocalName: "azure", Alias: "westeurope", Source: "registry.terraform.io/hashicorp/azurerm"},
+		{LocalName: "google", Source: "registry.terraform.io/hashicorp/google"},
+		{LocalName: "aws", Source: "registry.terraform.io/hashicorp/aws"},
+	}))
+	g.Expect(refs[0].Type()).To(Equal("azurerm"))
+
+	var none *ModuleProviders
+	g.Expect(none.Resolve(f.Body().Blocks()[0]).Source).To(Equal("registry.terraform.io/hashicorp/azurerm"))
+}
+
+func TestReferencedProviders(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(testProvidersFile), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), []byte(testResourcesFile+`
+provider "random" {}
+
+data "tls_certificate" "default" {}
+`), 0644)).To(Succeed())
+
+	providers, err := ReadModuleProviders(dir)
+	g.Expect(err).To(BeNil())
+	g.Expect(providers.Referenced).To(Equal([]string{
+		"registry.terraform.io/hashicorp/aws",
+		"registry.terraform.io/hashicorp/azurerm",
+		"registry.terraform.io/hashicorp/google",
+		"registry.terraform.io/hashicorp/random",
+		"registry.terraform.io/hashicorp/tls",
+		"registry.terraform.io/mycorp/azurerm",
+	}))
+}
+
+func TestProviderRanges(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "versions.tf"), []byte(testProvidersFile), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, LOCK_FILE_NAME), []byte(`
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.4.3"
+}
+
+provider "registry.terraform.io/hashicorp/google" {
+  version = "4.50.0"
+}
+`), 0644)).To(Succeed())
+
+	ranges, err := ProviderRanges(dir)
+	g.Expect(err).To(BeNil())
+	g.Expect(ranges).To(HaveLen(4))
+
+	// required_providers entries take precedence over the lock file
+	g.Expect(ranges["registry.terraform.io/hashicorp/google"].Filename).To(Equal(filepath.Join(dir, "versions.tf")))
+	g.Expect(ranges["registry.terraform.io/hashicorp/google"].Start.Line).To(Equal(12))
+	g.Expect(ranges["registry.terraform.io/mycorp/azurerm"].Start.Line).To(Equal(4))
+	g.Expect(ranges["registry.terraform.io/hashicorp/random"].Filename).To(Equal(filepath.Join(dir, LOCK_FILE_NAME)))
+	g.Expect(ranges["registry.terraform.io/hashicorp/random"].Start.Line).To(Equal(2))
+}
+
+func TestNormalizeSource(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(NormalizeSource("azurerm")).To(Equal("registry.terraform.io/hashicorp/azurerm"))
+	g.Expect(NormalizeSource("MyCorp/azurerm")).To(Equal("registry.terraform.io/mycorp/azurerm"))
+	g.Expect(NormalizeSource("example.com/mycorp/azurerm")).To(Equal("example.com/mycorp/azurerm"))
+}
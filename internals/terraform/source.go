@@ -0,0 +1,104 @@
+package terraform
+
+import (
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// SourceIndex remembers where each token of a parsed file was located in the
+// original source, so findings can still be located once remediations have
+// modified the file. Tokens created by remediations are not indexed
+type SourceIndex struct {
+	filename string
+	tokens   map[*hclwrite.Token]hcl.Range
+}
+
+func NewSourceIndex(filename string, f *hclwrite.File) *SourceIndex {
+	index := &SourceIndex{filename: filename, tokens: make(map[*hclwrite.Token]hcl.Range)}
+	pos := hcl.InitialPos
+
+	for _, token := range f.BuildTokens(nil) {
+		pos.Byte += token.SpacesBefore
+		pos.Column += token.SpacesBefore
+		start := pos
+		pos = advance(pos, token.Bytes)
+		index.tokens[token] = hcl.Range{Filename: filename, Start: start, End: pos}
+	}
+
+	return index
+}
+
+// BlockRange returns the range between the block type and its closing brace
+func (i *SourceIndex) BlockRange(block *hclwrite.Block) (hcl.Range, bool) {
+	tokens := block.BuildTokens(nil)
+	start, ok := i.first(tokens)
+	if !ok {
+		return hcl.Range{}, false
+	}
+
+	end, ok := i.ClosingBrace(block)
+	if !ok {
+		return hcl.Range{}, false
+	}
+
+	return hcl.RangeBetween(start, end), true
+}
+
+// AttributeRange returns the range between the attribute name and the end of its expression
+func (i *SourceIndex) AttributeRange(attribute *hclwrite.Attribute) (hcl.Range, bool) {
+	start, ok := i.first(attribute.BuildTokens(nil))
+	if !ok {
+		return hcl.Range{}, false
+	}
+
+	exprTokens := attribute.Expr().BuildTokens(nil)
+	if len(exprTokens) == 0 {
+		return hcl.Range{}, false
+	}
+
+	end, ok := i.tokens[exprTokens[len(exprTokens)-1]]
+	if !ok {
+		return hcl.Range{}, false
+	}
+
+	return hcl.RangeBetween(start, end), true
+}
+
+// ClosingBrace returns the range of the closing brace of the block
+func (i *SourceIndex) ClosingBrace(block *hclwrite.Block) (hcl.Range, bool) {
+	tokens := block.BuildTokens(nil)
+	for j := len(tokens) - 1; j >= 0; j-- {
+		if tokens[j].Type == hclsyntax.TokenCBrace {
+			r, ok := i.tokens[tokens[j]]
+			return r, ok
+		}
+	}
+	return hcl.Range{}, false
+}
+
+// first returns the range of the first indexed token that is not part of the leading comments
+func (i *SourceIndex) first(tokens hclwrite.Tokens) (hcl.Range, bool) {
+	for _, token := range tokens {
+		if token.Type == hclsyntax.TokenComment || token.Type == hclsyntax.TokenNewline {
+			continue
+		}
+		r, ok := i.tokens[token]
+		return r, ok
+	}
+	return hcl.Range{}, false
+}
+
+func advance(pos hcl.Pos, bytes []byte) hcl.Pos {
+	for _, b := range bytes {
+		pos.Byte++
+		if b == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else if b&0xC0 != 0x80 {
+			//utf-8 continuation bytes do not start a new column
+			pos.Column++
+		}
+	}
+	return pos
+}
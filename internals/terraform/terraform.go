@@ -10,7 +10,6 @@ import (
 	"os/exec"
 	"path/filepath"
 
-	"github.com/clearbank/terrapolicy/internals/utils"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2/hclwrite"
@@ -51,37 +50,87 @@ func ValidateInitRun(dir string) error {
 	return nil
 }
 
+// GetResourceType returns the type label of a resource block, or nothing if it has none
 func GetResourceType(resource *hclwrite.Block) string {
-	return resource.Labels()[0]
+	if labels := resource.Labels(); len(labels) > 0 {
+		return labels[0]
+	}
+	return ""
 }
 
-func GetTerraformFilePaths(dir string) ([]string, error) {
+// GetResourceName returns the name label of a resource block, or nothing if it has none
+func GetResourceName(resource *hclwrite.Block) string {
+	if labels := resource.Labels(); len(labels) > 1 {
+		return labels[1]
+	}
+	return ""
+}
+
+// InvalidResourceBlocks returns the resource and data blocks of a file without
+// exactly a type and a name label, which terraform rejects
+func InvalidResourceBlocks(f *hclwrite.File) []*hclwrite.Block {
+	var invalid []*hclwrite.Block
+	for _, block := range f.Body().Blocks() {
+		if block.Type() != "resource" && block.Type() != "data" {
+			continue
+		}
+
+		if len(block.Labels()) != 2 {
+			invalid = append(invalid, block)
+		}
+	}
+	return invalid
+}
+
+// TerraformFile is a terraform file of the root module or of one of its modules.
+// The root module has an empty key
+type TerraformFile struct {
+	Path   string
+	Module ModuleMetadata
+}
+
+func GetTerraformFiles(dir string) ([]TerraformFile, error) {
 	rootDir, tfFileMatcher := dir, "/*.tf"
-	tfFiles, err := file.GetFilePaths(rootDir + tfFileMatcher)
+	rootFiles, err := file.GetFilePaths(rootDir + tfFileMatcher)
 
 	if err != nil {
 		return nil, err
 	}
 
-	modulesDirs, err := getTerraformModulesDirPaths(rootDir)
+	var tfFiles []TerraformFile
+	seen := make(map[string]bool)
+	add := func(paths []string, module ModuleMetadata) {
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				tfFiles = append(tfFiles, TerraformFile{Path: path, Module: module})
+			}
+		}
+	}
+
+	add(rootFiles, ModuleMetadata{Dir: "."})
+
+	modules, err := getTerraformModules(rootDir)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, moduleDir := range modulesDirs {
-		matches, err := file.GetFilePaths(moduleDir + tfFileMatcher)
+	for _, module := range modules {
+		matches, err := file.GetFilePaths(module.Dir + tfFileMatcher)
 		if err != nil {
 			return nil, err
 		}
 
-		tfFiles = append(tfFiles, matches...)
+		add(matches, module)
 	}
 
-	return utils.UniqString(tfFiles), nil
+	return tfFiles, nil
 }
 
-func getTerraformModulesDirPaths(dir string) ([]string, error) {
-	var paths []string
+// getTerraformModules returns the modules listed in modules.json, with their
+// directory resolved
+func getTerraformModules(dir string) ([]ModuleMetadata, error) {
+	var modules []ModuleMetadata
 	var modulesJson ModulesJson
 
 	jsonFile, err := os.Open(dir + "/.terraform/modules/modules.json")
@@ -89,7 +138,7 @@ func getTerraformModulesDirPaths(dir string) ([]string, error) {
 	defer jsonFile.Close()
 
 	if os.IsNotExist(err) {
-		return paths, nil
+		return modules, nil
 	}
 
 	byteValue, err := ioutil.ReadAll(jsonFile)
@@ -113,8 +162,9 @@ func getTerraformModulesDirPaths(dir string) ([]string, error) {
 			return nil, err
 		}
 
-		paths = append(paths, modulePath)
+		module.Dir = modulePath
+		modules = append(modules, module)
 	}
 
-	return paths, nil
+	return modules, nil
 }
@@ -0,0 +1,314 @@
+package tfschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clearbank/terrapolicy/internals/terraform"
+
+	"github.com/minamijoyo/tfschema/tfschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var cacheDirLock sync.RWMutex
+var cacheDir string
+
+// SetCacheDir sets the directory resource type schemas are cached in, across
+// runs and root modules. An empty directory disables the cache
+func SetCacheDir(dir string) {
+	cacheDirLock.Lock()
+	defer cacheDirLock.Unlock()
+	cacheDir = dir
+}
+
+func getCacheDir() string {
+	cacheDirLock.RLock()
+	defer cacheDirLock.RUnlock()
+	return cacheDir
+}
+
+// DefaultCacheDir returns the terrapolicy directory of the user cache directory,
+// or nothing if the user has none
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "terrapolicy")
+}
+
+// schemaCache holds the schemas of a provider version on a platform, a file per resource type:
+// <cache dir>/registry.terraform.io/hashicorp/azurerm/3.0.0/linux_amd64/azurerm_storage_account.json
+type schemaCache struct {
+	dir string
+}
+
+// cachedSchema also records the resource types the provider does not support
+type cachedSchema struct {
+	Found bool         `json:"found"`
+	Block *cachedBlock `json:"block,omitempty"`
+}
+
+// cachedBlock mirrors tfschema.Block, which cannot be decoded from json
+type cachedBlock struct {
+	Attributes map[string]*cachedAttribute   `json:"attributes,omitempty"`
+	BlockTypes map[string]*cachedNestedBlock `json:"block_types,omitempty"`
+}
+
+type cachedAttribute struct {
+	Type      cty.Type `json:"type"`
+	Required  bool     `json:"required,omitempty"`
+	Optional  bool     `json:"optional,omitempty"`
+	Computed  bool     `json:"computed,omitempty"`
+	Sensitive bool     `json:"sensitive,omitempty"`
+}
+
+type cachedNestedBlock struct {
+	cachedBlock
+	Nesting  int `json:"nesting"`
+	MinItems int `json:"min_items,omitempty"`
+	MaxItems int `json:"max_items,omitempty"`
+}
+
+// newSchemaCache returns the cache of the locked provider version, or nil if the
+// cache is disabled or the provider is not locked
+func newSchemaCache(locked *terraform.LockedProvider) *schemaCache {
+	dir := getCacheDir()
+	if dir == "" || locked == nil {
+		return nil
+	}
+
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	return &schemaCache{dir: filepath.Join(dir, filepath.FromSlash(locked.Address), locked.Version, platform)}
+}
+
+// get returns the cached schema of the resource type, nil if the provider does
+// not support it, and whether it was cached
+func (c *schemaCache) get(resourceType string) (*tfschema.Block, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(c.path(resourceType))
+	if err != nil {
+		return nil, false
+	}
+
+	var cached cachedSchema
+	if err := json.Unmarshal(content, &cached); err != nil {
+		log.Printf("[WARN] ignoring invalid cached schema %v: %v", c.path(resourceType), err)
+		return nil, false
+	}
+
+	// the modification time of the directory tracks its last use, for pruning
+	now := time.Now()
+	_ = os.Chtimes(c.dir, now, now)
+
+	log.Printf("[DEBUG] using cached schema of %v", resourceType)
+	if !cached.Found {
+		return nil, true
+	}
+	return cached.Block.toBlock(), true
+}
+
+// put caches the schema of the resource type. A nil schema records that the
+// provider does not support the resource type
+func (c *schemaCache) put(resourceType string, block *tfschema.Block) {
+	if c == nil {
+		return
+	}
+
+	cached := cachedSchema{Found: block != nil}
+	if block != nil {
+		cached.Block = fromBlock(block)
+	}
+
+	if err := c.write(resourceType, cached); err != nil {
+		log.Printf("[WARN] cannot cache schema of %v: %v", resourceType, err)
+	}
+}
+
+func (c *schemaCache) write(resourceType string, cached cachedSchema) error {
+	content, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	// renamed once complete, so that concurrent runs never read a partial file
+	tmp, err := os.CreateTemp(c.dir, resourceType+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), c.path(resourceType))
+}
+
+func (c *schemaCache) path(resourceType string) string {
+	return filepath.Join(c.dir, resourceType+".json")
+}
+
+func fromBlock(block *tfschema.Block) *cachedBlock {
+	cached := &cachedBlock{
+		Attributes: make(map[string]*cachedAttribute, len(block.Attributes)),
+		BlockTypes: make(map[string]*cachedNestedBlock, len(block.BlockTypes)),
+	}
+
+	for name, attribute := range block.Attributes {
+		cached.Attributes[name] = &cachedAttribute{
+			Type:      attribute.Type.Type,
+			Required:  attribute.Required,
+			Optional:  attribute.Optional,
+			Computed:  attribute.Computed,
+			Sensitive: attribute.Sensitive,
+		}
+	}
+
+	for name, nested := range block.BlockTypes {
+		cached.BlockTypes[name] = &cachedNestedBlock{
+			cachedBlock: *fromBlock(&nested.Block),
+			Nesting:     int(nested.Nesting),
+			MinItems:    nested.MinItems,
+			MaxItems:    nested.MaxItems,
+		}
+	}
+
+	return cached
+}
+
+func (c *cachedBlock) toBlock() *tfschema.Block {
+	block := &tfschema.Block{
+		Attributes: make(map[string]*tfschema.Attribute, len(c.Attributes)),
+		BlockTypes: make(map[string]*tfschema.NestedBlock, len(c.BlockTypes)),
+	}
+
+	for name, attribute := range c.Attributes {
+		block.Attributes[name] = &tfschema.Attribute{
+			Type:      tfschema.Type{Type: attribute.Type},
+			Required:  attribute.Required,
+			Optional:  attribute.Optional,
+			Computed:  attribute.Computed,
+			Sensitive: attribute.Sensitive,
+		}
+	}
+
+	for name, nested := range c.BlockTypes {
+		block.BlockTypes[name] = &tfschema.NestedBlock{
+			Block:    *nested.cachedBlock.toBlock(),
+			MinItems: nested.MinItems,
+			MaxItems: nested.MaxItems,
+		}
+		setNesting(&block.BlockTypes[name].Nesting, nested.Nesting)
+	}
+
+	return block
+}
+
+// CachedProvider is a provider version whose schemas are cached for a platform
+type CachedProvider struct {
+	Address  string
+	Version  string
+	Platform string
+	Dir      string
+	LastUsed time.Time
+}
+
+func (p CachedProvider) String() string {
+	return fmt.Sprintf("%v %v (%v)", p.Address, p.Version, p.Platform)
+}
+
+// ListCache returns the provider versions cached in dir
+func ListCache(dir string) ([]CachedProvider, error) {
+	var providers []CachedProvider
+
+	// <host>/<namespace>/<name>/<version>/<platform>
+	matches, err := filepath.Glob(filepath.Join(dir, "*", "*", "*", "*", "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			continue
+		}
+
+		rel, err := filepath.Rel(dir, match)
+		if err != nil {
+			return nil, err
+		}
+
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		providers = append(providers, CachedProvider{
+			Address:  strings.Join(parts[:3], "/"),
+			Version:  parts[3],
+			Platform: parts[4],
+			Dir:      match,
+			LastUsed: info.ModTime(),
+		})
+	}
+
+	return providers, nil
+}
+
+// PruneCache removes the provider versions of the cache in dir not used since
+// maxAge, and returns them. A zero maxAge removes every provider version
+func PruneCache(dir string, maxAge time.Duration, now time.Time) ([]CachedProvider, error) {
+	providers, err := ListCache(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []CachedProvider
+	for _, provider := range providers {
+		if maxAge > 0 && now.Sub(provider.LastUsed) < maxAge {
+			continue
+		}
+
+		if err := os.RemoveAll(provider.Dir); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, provider)
+	}
+
+	removeEmptyDirs(dir)
+	return pruned, nil
+}
+
+// removeEmptyDirs removes the directories left empty under dir, deepest first
+func removeEmptyDirs(dir string) {
+	var dirs []string
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() && path != dir {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		_ = os.Remove(dirs[i]) // fails unless empty
+	}
+}
@@ -0,0 +1,116 @@
+package tfschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfschema/tfschema"
+	"github.com/zclconf/go-cty/cty"
+
+	. "github.com/onsi/gomega"
+)
+
+const azurerm = "registry.terraform.io/hashicorp/azurerm"
+
+const testLockFile = `
+provider "registry.terraform.io/hashicorp/azurerm" {
+  version     = "3.0.0"
+  constraints = "~> 3.0"
+}
+`
+
+func setupCache(t *testing.T) (string, string) {
+	g := NewWithT(t)
+
+	cacheDir, rootDir := t.TempDir(), t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(rootDir, ".terraform.lock.hcl"), []byte(testLockFile), 0644)).To(Succeed())
+
+	SetCacheDir(cacheDir)
+	t.Cleanup(func() { SetCacheDir("") })
+
+	return cacheDir, rootDir
+}
+
+func TestSchemaCache(t *testing.T) {
+	g := NewWithT(t)
+	cacheDir, rootDir := setupCache(t)
+
+	cache := newSchemaCache(lockedProvider(azurerm, rootDir))
+	g.Expect(cache).ToNot(BeNil())
+	g.Expect(newSchemaCache(lockedProvider("registry.terraform.io/hashicorp/aws", rootDir))).To(BeNil())
+
+	block := &tfschema.Block{
+		Attributes: map[string]*tfschema.Attribute{
+			"name": {Type: tfschema.Type{Type: cty.String}, Required: true},
+			"tags": {Type: tfschema.Type{Type: cty.Map(cty.String)}, Optional: true},
+		},
+		BlockTypes: map[string]*tfschema.NestedBlock{
+			"network_rules": {
+				Block: tfschema.Block{
+					Attributes: map[string]*tfschema.Attribute{
+						"ip_rules": {Type: tfschema.Type{Type: cty.Set(cty.String)}, Optional: true},
+					},
+					BlockTypes: map[string]*tfschema.NestedBlock{},
+				},
+				Nesting:  NESTING_LIST,
+				MaxItems: 1,
+			},
+		},
+	}
+
+	_, cached := cache.get("azurerm_storage_account")
+	g.Expect(cached).To(BeFalse())
+
+	cache.put("azurerm_storage_account", block)
+	cache.put("azurerm_unknown", nil)
+
+	g.Expect(filepath.Join(cacheDir, "registry.terraform.io", "hashicorp", "azurerm", "3.0.0")).To(BeADirectory())
+
+	schema, cached := newSchemaCache(lockedProvider(azurerm, rootDir)).get("azurerm_storage_account")
+	g.Expect(cached).To(BeTrue())
+	g.Expect(schema).To(Equal(block))
+
+	schema, cached = cache.get("azurerm_unknown")
+	g.Expect(cached).To(BeTrue())
+	g.Expect(schema).To(BeNil())
+}
+
+func TestSchemaCacheDisabled(t *testing.T) {
+	g := NewWithT(t)
+	_, rootDir := setupCache(t)
+
+	SetCacheDir("")
+	cache := newSchemaCache(lockedProvider(azurerm, rootDir))
+	g.Expect(cache).To(BeNil())
+
+	cache.put("azurerm_storage_account", &tfschema.Block{})
+	_, cached := cache.get("azurerm_storage_account")
+	g.Expect(cached).To(BeFalse())
+}
+
+func TestPruneCache(t *testing.T) {
+	g := NewWithT(t)
+	cacheDir, rootDir := setupCache(t)
+
+	newSchemaCache(lockedProvider(azurerm, rootDir)).put("azurerm_storage_account", &tfschema.Block{})
+
+	providers, err := ListCache(cacheDir)
+	g.Expect(err).To(BeNil())
+	g.Expect(providers).To(HaveLen(1))
+	g.Expect(providers[0].Address).To(Equal("registry.terraform.io/hashicorp/azurerm"))
+	g.Expect(providers[0].Version).To(Equal("3.0.0"))
+
+	pruned, err := PruneCache(cacheDir, time.Hour, time.Now())
+	g.Expect(err).To(BeNil())
+	g.Expect(pruned).To(BeEmpty())
+
+	pruned, err = PruneCache(cacheDir, time.Hour, time.Now().Add(2*time.Hour))
+	g.Expect(err).To(BeNil())
+	g.Expect(pruned).To(HaveLen(1))
+
+	entries, err := os.ReadDir(cacheDir)
+	g.Expect(err).To(BeNil())
+	g.Expect(entries).To(BeEmpty())
+}
@@ -0,0 +1,246 @@
+package tfschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/clearbank/terrapolicy/internals/terraform"
+
+	"github.com/minamijoyo/tfschema/tfschema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// fileSource holds the schemas of a file output by `terraform providers schema -json`
+type fileSource struct {
+	// path is the absolute path of the file
+	path string
+	// providers holds the resource schemas by provider source address
+	providers map[string]map[string]*Block
+	// resources holds the resource schemas of the first provider declaring each
+	// resource type, in the order of their addresses
+	resources map[string]*Block
+}
+
+type providersSchemaJson struct {
+	FormatVersion   string                         `json:"format_version"`
+	ProviderSchemas map[string]*providerSchemaJson `json:"provider_schemas"`
+}
+
+type providerSchemaJson struct {
+	ResourceSchemas map[string]*struct {
+		Block *blockJson `json:"block"`
+	} `json:"resource_schemas"`
+}
+
+type blockJson struct {
+	Attributes map[string]*attributeJson `json:"attributes"`
+	BlockTypes map[string]*blockTypeJson `json:"block_types"`
+}
+
+type attributeJson struct {
+	// Type is not set for the attributes of nested types
+	Type       cty.Type        `json:"type"`
+	NestedType *nestedTypeJson `json:"nested_type"`
+	Required   bool            `json:"required"`
+	Optional   bool            `json:"optional"`
+	Computed   bool            `json:"computed"`
+	Sensitive  bool            `json:"sensitive"`
+}
+
+type nestedTypeJson struct {
+	Attributes  map[string]*attributeJson `json:"attributes"`
+	NestingMode string                    `json:"nesting_mode"`
+}
+
+type blockTypeJson struct {
+	NestingMode string     `json:"nesting_mode"`
+	Block       *blockJson `json:"block"`
+	MinItems    int        `json:"min_items"`
+	MaxItems    int        `json:"max_items"`
+}
+
+// the nesting modes of tfschema.NestedBlock, numbered as terraform configschema
+// numbers them, so that terraform itself is not a dependency
+const (
+	NESTING_SINGLE = iota + 1
+	NESTING_GROUP
+	NESTING_LIST
+	NESTING_SET
+	NESTING_MAP
+)
+
+var NESTING_MODES = map[string]int{
+	"single": NESTING_SINGLE,
+	"group":  NESTING_GROUP,
+	"list":   NESTING_LIST,
+	"set":    NESTING_SET,
+	"map":    NESTING_MAP,
+}
+
+// setNesting sets the nesting mode of a tfschema.NestedBlock, whose type is declared by terraform
+func setNesting[T ~int](nesting *T, mode int) {
+	*nesting = T(mode)
+}
+
+// LoadSchemaFile loads the resource schemas of a file output by
+// `terraform providers schema -json`, so that no provider plugin is needed
+func LoadSchemaFile(path string) (SchemaSource, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas providersSchemaJson
+	if err := json.Unmarshal(content, &schemas); err != nil {
+		return nil, fmt.Errorf("invalid schema file %v: %v", path, err)
+	}
+
+	if schemas.FormatVersion == "" {
+		return nil, fmt.Errorf("invalid schema file %v: missing format_version", path)
+	}
+
+	var addresses []string
+	for address := range schemas.ProviderSchemas {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	source := fileSource{path: abs, providers: make(map[string]map[string]*Block), resources: make(map[string]*Block)}
+	for _, address := range addresses {
+		resources := make(map[string]*Block)
+		source.providers[terraform.NormalizeSource(address)] = resources
+
+		for resourceType, resourceSchema := range schemas.ProviderSchemas[address].ResourceSchemas {
+			if resourceSchema.Block == nil {
+				continue
+			}
+
+			block, err := resourceSchema.Block.toBlock()
+			if err != nil {
+				return nil, fmt.Errorf("invalid schema of %v in %v: %v", resourceType, path, err)
+			}
+
+			resources[resourceType] = block
+			if _, exists := source.resources[resourceType]; !exists {
+				source.resources[resourceType] = block
+			}
+		}
+	}
+
+	return source, nil
+}
+
+// SchemaFile returns the absolute path of the schema file the source was loaded
+// from, or nothing if it was not loaded by LoadSchemaFile
+func SchemaFile(source SchemaSource) string {
+	if s, ok := source.(fileSource); ok {
+		return s.path
+	}
+	return ""
+}
+
+// ResourceSchema returns the schema of the resource type of the provider, or of
+// any provider if the file has no schema of the provider
+func (s fileSource) ResourceSchema(ref ProviderRef, resourceType string) (*Block, error) {
+	if resources, ok := s.providers[ref.Source]; ok {
+		block, ok := resources[resourceType]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return block, nil
+	}
+
+	block, ok := s.resources[resourceType]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return block, nil
+}
+
+func (b *blockJson) toBlock() (*Block, error) {
+	block := &tfschema.Block{
+		Attributes: make(map[string]*tfschema.Attribute, len(b.Attributes)),
+		BlockTypes: make(map[string]*tfschema.NestedBlock, len(b.BlockTypes)),
+	}
+
+	for name, attribute := range b.Attributes {
+		attributeType, err := attribute.impliedType()
+		if err != nil {
+			return nil, err
+		}
+
+		block.Attributes[name] = &tfschema.Attribute{
+			Type:      tfschema.Type{Type: attributeType},
+			Required:  attribute.Required,
+			Optional:  attribute.Optional,
+			Computed:  attribute.Computed,
+			Sensitive: attribute.Sensitive,
+		}
+	}
+
+	for name, blockType := range b.BlockTypes {
+		nesting, ok := NESTING_MODES[blockType.NestingMode]
+		if !ok {
+			return nil, fmt.Errorf("unknown nesting mode of %v: %v", name, blockType.NestingMode)
+		}
+
+		nested := &blockJson{}
+		if blockType.Block != nil {
+			nested = blockType.Block
+		}
+
+		nestedBlock, err := nested.toBlock()
+		if err != nil {
+			return nil, err
+		}
+
+		block.BlockTypes[name] = &tfschema.NestedBlock{
+			Block:    *nestedBlock,
+			MinItems: blockType.MinItems,
+			MaxItems: blockType.MaxItems,
+		}
+		setNesting(&block.BlockTypes[name].Nesting, nesting)
+	}
+
+	return block, nil
+}
+
+// impliedType returns the type of the attribute, the object type of its
+// attributes for nested types
+func (a *attributeJson) impliedType() (cty.Type, error) {
+	if a.NestedType == nil {
+		return a.Type, nil
+	}
+
+	attributes := make(map[string]cty.Type, len(a.NestedType.Attributes))
+	for name, attribute := range a.NestedType.Attributes {
+		attributeType, err := attribute.impliedType()
+		if err != nil {
+			return cty.NilType, err
+		}
+		attributes[name] = attributeType
+	}
+
+	object := cty.Object(attributes)
+	switch a.NestedType.NestingMode {
+	case "single", "group":
+		return object, nil
+	case "list":
+		return cty.List(object), nil
+	case "set":
+		return cty.Set(object), nil
+	case "map":
+		return cty.Map(object), nil
+	default:
+		return cty.NilType, fmt.Errorf("unknown nesting mode: %v", a.NestedType.NestingMode)
+	}
+}
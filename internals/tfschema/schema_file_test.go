@@ -0,0 +1,97 @@
+package tfschema
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+
+	. "github.com/onsi/gomega"
+)
+
+const testSchemaFile = `{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/hashicorp/azurerm": {
+      "provider": {"version": 0, "block": {}},
+      "resource_schemas": {
+        "azurerm_storage_account": {
+          "version": 3,
+          "block": {
+            "attributes": {
+              "name": {"type": "string", "required": true},
+              "tags": {"type": ["map", "string"], "optional": true},
+              "identity": {
+                "nested_type": {
+                  "nesting_mode": "list",
+                  "attributes": {"type": {"type": "string", "required": true}}
+                },
+                "optional": true
+              }
+            },
+            "block_types": {
+              "network_rules": {
+                "nesting_mode": "list",
+                "block": {
+                  "attributes": {"ip_rules": {"type": ["set", "string"], "optional": true}}
+                },
+                "max_items": 1
+              }
+            }
+          }
+        }
+      }
+    },
+    "registry.terraform.io/mycorp/azurerm": {
+      "resource_schemas": {
+        "azurerm_storage_account": {
+          "block": {"attributes": {"name": {"type": "number", "optional": true}}}
+        }
+      }
+    }
+  }
+}`
+
+func TestLoadSchemaFile(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	g.Expect(os.WriteFile(path, []byte(testSchemaFile), 0644)).To(Succeed())
+
+	source, err := LoadSchemaFile(path)
+	g.Expect(err).To(BeNil())
+
+	schema, err := source.ResourceSchema(ProviderRef{LocalName: "azurerm", Source: azurerm}, "azurerm_storage_account")
+	g.Expect(err).To(BeNil())
+	g.Expect(schema.Attributes["name"].Type.Type).To(Equal(cty.String))
+	g.Expect(schema.Attributes["name"].Required).To(BeTrue())
+	g.Expect(schema.Attributes["tags"].Type.Type).To(Equal(cty.Map(cty.String)))
+	g.Expect(schema.Attributes["identity"].Type.Type).To(Equal(cty.List(cty.Object(map[string]cty.Type{"type": cty.String}))))
+
+	networkRules := schema.BlockTypes["network_rules"]
+	g.Expect(int(networkRules.Nesting)).To(Equal(NESTING_LIST))
+	g.Expect(networkRules.MaxItems).To(Equal(1))
+	g.Expect(networkRules.Attributes["ip_rules"].Type.Type).To(Equal(cty.Set(cty.String)))
+
+	_, err = source.ResourceSchema(ProviderRef{LocalName: "azurerm", Source: azurerm}, "azurerm_unknown")
+	g.Expect(err).To(MatchError("not found"))
+
+	schema, err = source.ResourceSchema(ProviderRef{LocalName: "azurerm", Source: "registry.terraform.io/mycorp/azurerm"}, "azurerm_storage_account")
+	g.Expect(err).To(BeNil())
+	g.Expect(schema.Attributes["name"].Type.Type).To(Equal(cty.Number))
+
+	schema, err = source.ResourceSchema(ProviderRef{LocalName: "azure", Source: "example.com/other/azure"}, "azurerm_storage_account")
+	g.Expect(err).To(BeNil())
+	g.Expect(schema.Attributes["name"].Type.Type).To(Equal(cty.String))
+}
+
+func TestLoadSchemaFileInvalid(t *testing.T) {
+	g := NewWithT(t)
+
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	g.Expect(os.WriteFile(path, []byte(`{"provider_schemas": {}}`), 0644)).To(Succeed())
+
+	_, err := LoadSchemaFile(path)
+	g.Expect(err).To(MatchError(ContainSubstring("missing format_version")))
+}
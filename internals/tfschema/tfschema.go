@@ -4,33 +4,78 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 
-	"github.com/clearbank/terrapolicy/internals/providers"
 	"github.com/clearbank/terrapolicy/internals/terraform"
-	"github.com/clearbank/terrapolicy/internals/utils"
 
 	"github.com/hashicorp/go-hclog"
-	"github.com/hashicorp/hcl/v2/hclwrite"
 	"github.com/minamijoyo/tfschema/tfschema"
 )
 
+// providerSchemas holds the schema cache and client of a provider of a root
+// module. Each is initialised once, whichever evaluation first needs it.
+// Initialising other providers does not wait for it
+type providerSchemas struct {
+	initOnce  sync.Once
+	cache     *schemaCache
+	pluginDir string
+
+	clientOnce sync.Once
+	client     tfschema.Client
+	err        error
+}
+
 var providerToClientMapLock sync.Mutex
-var providerToClientMap = map[string]tfschema.Client{}
+var providerToClientMap = map[string]*providerSchemas{}
 
 //export forward
 type Block = tfschema.Block
 
-func GetSchemaForBlock(resource *hclwrite.Block, rootDir string) (*tfschema.Block, error) {
-	resourceType := terraform.GetResourceType(resource)
-	providerName, ok := isResourceSupported(resourceType)
-	if !ok {
-		log.Printf("[WARN] Resource %v not supported", resourceType)
-		return nil, errors.New("not supported")
+// ProviderRef identifies the provider of a resource
+type ProviderRef = terraform.ProviderRef
+
+// SchemaSource provides the schemas of resource types
+type SchemaSource interface {
+	// ResourceSchema returns the schema of the resource type of the provider, or an error if it is unknown
+	ResourceSchema(provider ProviderRef, resourceType string) (*Block, error)
+}
+
+// pluginSource retrieves the schemas from the provider plugins installed by terraform init
+type pluginSource struct {
+	rootDir string
+}
+
+// NewPluginSource returns the schemas of the provider plugins installed in the
+// root module, cached as set by SetCacheDir
+func NewPluginSource(rootDir string) SchemaSource {
+	return pluginSource{rootDir: rootDir}
+}
+
+func (s pluginSource) ResourceSchema(ref ProviderRef, resourceType string) (*Block, error) {
+	if ref.LocalName == "" {
+		return nil, fmt.Errorf("failed to detect a provider name: %s", resourceType)
 	}
 
-	client, err := getTfSchemaClient(providerName, rootDir)
+	provider := getProviderSchemas(ref.Source, s.rootDir)
+	provider.initOnce.Do(func() {
+		locked := lockedProvider(ref.Source, s.rootDir)
+		provider.cache = newSchemaCache(locked)
+		provider.pluginDir = pluginDir(locked, s.rootDir)
+	})
+
+	if typeSchema, cached := provider.cache.get(resourceType); cached {
+		if typeSchema == nil {
+			log.Print("[WARN] Skipped ", resourceType, " as it is not YET supported")
+			return nil, errors.New("not found")
+		}
+		return typeSchema, nil
+	}
+
+	client, err := provider.getClient(ref)
 
 	if err != nil {
 		return nil, err
@@ -41,53 +86,80 @@ func GetSchemaForBlock(resource *hclwrite.Block, rootDir string) (*tfschema.Bloc
 	if err != nil {
 		if strings.Contains(err.Error(), "Failed to find resource type") {
 			log.Print("[WARN] Skipped ", resourceType, " as it is not YET supported")
+			provider.cache.put(resourceType, nil)
 			return nil, errors.New("not found")
 		}
 
 		return nil, err
 	}
 
+	provider.cache.put(resourceType, typeSchema)
 	return typeSchema, nil
 }
 
-func getTfSchemaClient(providerName string, rootDir string) (tfschema.Client, error) {
-	providerToClientMapLock.Lock()
-	defer providerToClientMapLock.Unlock()
-
-	hashKey := fmt.Sprintf("%v:%v", rootDir, providerName)
-	client, exists := providerToClientMap[hashKey]
+// lockedProvider returns the provider of the lock file of the root module with
+// the source address, or nil if it is not locked
+func lockedProvider(source string, rootDir string) *terraform.LockedProvider {
+	lockedProviders, err := terraform.ReadLockFile(rootDir)
+	if err != nil {
+		log.Printf("[WARN] cannot read lock file: %v", err)
+		return nil
+	}
 
-	if exists {
-		log.Printf("[DEBUG] Using cached client for provider %v", hashKey)
-		return client, nil
+	for _, locked := range lockedProviders {
+		if locked.Address == source {
+			return &locked
+		}
 	}
 
-	log.Printf("[DEBUG] Initiating client for provider %v", hashKey)
+	log.Printf("[DEBUG] provider %v not locked", source)
+	return nil
+}
 
-	logger := hclog.New(&hclog.LoggerOptions{
-		Name:   "plugin",
-		Level:  hclog.Trace,
-		Output: hclog.DefaultOutput,
-	})
+// pluginDir returns the directory terraform init installed the locked provider
+// version in. Otherwise, the plugin is looked up from the root module
+func pluginDir(locked *terraform.LockedProvider, rootDir string) string {
+	if locked == nil {
+		return rootDir
+	}
 
-	newClient, err := tfschema.NewClient(providerName, tfschema.Option{
-		RootDir: rootDir,
-		Logger:  logger,
-	})
-	if err != nil {
-		return nil, err
+	dir := filepath.Join(rootDir, ".terraform", "providers", filepath.FromSlash(locked.Address), locked.Version, runtime.GOOS+"_"+runtime.GOARCH)
+	if _, err := os.Stat(dir); err != nil {
+		log.Printf("[DEBUG] provider %v not installed in %v", locked.Address, dir)
+		return rootDir
 	}
+	return dir
+}
 
-	providerToClientMap[hashKey] = newClient
+func getProviderSchemas(source string, rootDir string) *providerSchemas {
+	hashKey := fmt.Sprintf("%v:%v", rootDir, source)
 
-	return newClient, nil
-}
+	providerToClientMapLock.Lock()
+	defer providerToClientMapLock.Unlock()
 
-func isResourceSupported(resourceType string) (string, bool) {
-	providerName, err := providers.ExtractProviderNameFromResourceType(resourceType)
-	if err != nil {
-		return "", false
+	provider, exists := providerToClientMap[hashKey]
+	if !exists {
+		provider = &providerSchemas{}
+		providerToClientMap[hashKey] = provider
 	}
+	return provider
+}
+
+func (p *providerSchemas) getClient(ref ProviderRef) (tfschema.Client, error) {
+	p.clientOnce.Do(func() {
+		log.Printf("[DEBUG] Initiating client for provider %v from %v", ref.Source, p.pluginDir)
+
+		logger := hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin",
+			Level:  hclog.Trace,
+			Output: hclog.DefaultOutput,
+		})
+
+		p.client, p.err = tfschema.NewClient(ref.Type(), tfschema.Option{
+			RootDir: p.pluginDir,
+			Logger:  logger,
+		})
+	})
 
-	return providerName, utils.Contains(providers.SUPPORTED_PROVIDERS, providerName)
+	return p.client, p.err
 }
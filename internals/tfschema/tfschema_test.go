@@ -0,0 +1,47 @@
+package tfschema
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/clearbank/terrapolicy/internals/terraform"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPluginSource(t *testing.T) {
+	g := NewWithT(t)
+	source := NewPluginSource(t.TempDir())
+
+	for resourceType, provider := range map[string]string{
+		"aws_s3_bucket":           "aws",
+		"google_storage_bucket":   "google",
+		"azurerm_storage_account": "azurerm",
+	} {
+		_, err := source.ResourceSchema(ProviderRef{LocalName: provider, Source: terraform.NormalizeSource(provider)}, resourceType)
+		g.Expect(err).To(MatchError(ContainSubstring("Failed to find plugin: "+provider+".")), resourceType)
+	}
+
+	_, err := source.ResourceSchema(ProviderRef{}, "bucket")
+	g.Expect(err).To(MatchError(ContainSubstring("failed to detect a provider name")))
+}
+
+func TestPluginSourceLockedProvider(t *testing.T) {
+	g := NewWithT(t)
+
+	rootDir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(rootDir, ".terraform.lock.hcl"), []byte(`
+provider "registry.terraform.io/mycorp/azurerm" {
+  version = "1.2.0"
+}
+`), 0644)).To(Succeed())
+
+	pluginDir := filepath.Join(rootDir, ".terraform", "providers", "registry.terraform.io", "mycorp", "azurerm", "1.2.0", runtime.GOOS+"_"+runtime.GOARCH)
+	g.Expect(os.MkdirAll(pluginDir, 0755)).To(Succeed())
+
+	ref := ProviderRef{LocalName: "azurerm", Source: "registry.terraform.io/mycorp/azurerm"}
+	_, err := NewPluginSource(rootDir).ResourceSchema(ref, "azurerm_storage_account")
+	g.Expect(err).To(MatchError(ContainSubstring("Failed to find plugin: azurerm. Plugin binary was not found in any of the following directories: [" + pluginDir + ",")))
+}
@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"github.com/clearbank/terrapolicy/policies"
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// Client is a running plugin binary
+type Client struct {
+	config  policies.PluginConfig
+	client  *goplugin.Client
+	service resourcePoliciesServer
+}
+
+// Load launches the plugin binary. The plugin runs until Kill or Cleanup is called
+func Load(config policies.PluginConfig) (*Client, error) {
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          goplugin.PluginSet{plugin_name: &resourcePoliciesPlugin{}},
+		Cmd:              exec.Command(config.Path),
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		Managed:          true,
+		Stderr:           log.Writer(),
+		Logger:           hclog.New(&hclog.LoggerOptions{Name: "plugin", Output: hclog.DefaultOutput, Level: hclog.Warn}),
+	})
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("cannot launch plugin %v: %v", config.Describe(), err)
+	}
+
+	raw, err := rpcClient.Dispense(plugin_name)
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("cannot dispense plugin %v: %v", config.Describe(), err)
+	}
+
+	return &Client{config: config, client: client, service: raw.(resourcePoliciesServer)}, nil
+}
+
+// Executors returns the executors of the resource policy types served by the plugin
+func (c *Client) Executors() (map[string]policies.ResourcePolicyExecutor, error) {
+	response, err := c.service.Schemas(context.Background(), &SchemasRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot retrieve the policy types of plugin %v: %v", c.config.Describe(), err)
+	}
+
+	executors := make(map[string]policies.ResourcePolicyExecutor, len(response.Schemas))
+	for name, params := range response.Schemas {
+		e := &executor{service: c.service, plugin: c.config.Describe(), policyType: name, schema: policies.PolicySchema{Params: params}}
+		if response.Checks[name] {
+			e.schema.Check = e.check
+		}
+		if response.Conflicts[name] {
+			e.schema.Conflict = e.conflict
+		}
+		executors[name] = e
+	}
+	return executors, nil
+}
+
+func (c *Client) Kill() {
+	c.client.Kill()
+}
+
+// Cleanup kills every plugin launched by Load
+func Cleanup() {
+	goplugin.CleanupClients()
+}
+
+// executor evaluates a policy type served by a plugin
+type executor struct {
+	service    resourcePoliciesServer
+	plugin     string
+	policyType string
+	schema     policies.PolicySchema
+}
+
+func (e *executor) Schema() policies.PolicySchema {
+	return e.schema
+}
+
+// check lints a policy block in the plugin. A failed call is reported as a problem of the block
+func (e *executor) check(block policies.PolicyBlock) []string {
+	response, err := e.service.Check(context.Background(), &CheckRequest{Type: e.policyType, Policy: block})
+	if err != nil {
+		return []string{fmt.Sprintf("cannot check the block with plugin %v: %v", e.plugin, err)}
+	}
+	return response.Problems
+}
+
+// conflict lints two policy blocks in the plugin. A failed call is logged, the
+// blocks are then not reported as conflicting
+func (e *executor) conflict(a policies.PolicyBlock, b policies.PolicyBlock) (string, bool) {
+	response, err := e.service.Conflict(context.Background(), &ConflictRequest{Type: e.policyType, A: a, B: b})
+	if err != nil {
+		log.Printf("[ERROR] cannot check the conflicts of policy %v of plugin %v: %v", e.policyType, e.plugin, err)
+		return "", false
+	}
+	return response.Reason, response.Conflict
+}
+
+func (e *executor) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	response, err := e.service.Execute(context.Background(), &ExecuteRequest{
+		Type:       e.policyType,
+		Policy:     payload.Policy,
+		File:       payload.Hcl.Bytes(),
+		FileName:   payload.FileName,
+		FilePath:   payload.FilePath,
+		WorkingDir: payload.WorkingDir,
+		Flags:      payload.Flags,
+		SchemaFile: tfschema.SchemaFile(payload.Schemas),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("policy %v of plugin %v failed: %v", e.policyType, e.plugin, err)
+	}
+
+	if len(response.File) > 0 {
+		if payload.Rewrite == nil {
+			return nil, fmt.Errorf("policy %v of plugin %v cannot rewrite %v", e.policyType, e.plugin, payload.FilePath)
+		}
+		if err := payload.Rewrite(response.File); err != nil {
+			return nil, fmt.Errorf("policy %v of plugin %v rewrote an invalid file: %v", e.policyType, e.plugin, err)
+		}
+	}
+
+	return response.Results, nil
+}
@@ -0,0 +1,193 @@
+// Package plugin runs resource policy executors out of process, in the style of
+// terraform providers. A plugin binary calls Serve with its executors and is
+// declared in the plugins section of the policy file
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/clearbank/terrapolicy/policies"
+	goplugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// PROTOCOL_VERSION must be increased on any breaking change of the messages
+const PROTOCOL_VERSION = 1
+
+const (
+	plugin_name  = "resource_policies"
+	codec_name   = "json"
+	service_name = "terrapolicy.ResourcePolicies"
+)
+
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  PROTOCOL_VERSION,
+	MagicCookieKey:   "TERRAPOLICY_PLUGIN",
+	MagicCookieValue: "5c1b4d6e-terrapolicy-resource-policies",
+}
+
+type SchemasRequest struct{}
+
+// SchemasResponse holds the params of every policy type served, and whether the
+// policy type checks its blocks or the conflicts between them, see CheckRequest
+// and ConflictRequest
+type SchemasResponse struct {
+	Schemas   map[string]map[string]policies.ParamSchema `json:"schemas"`
+	Checks    map[string]bool                            `json:"checks,omitempty"`
+	Conflicts map[string]bool                            `json:"conflicts,omitempty"`
+}
+
+// CheckRequest lints a policy block with the Check of its policy type
+type CheckRequest struct {
+	Type   string               `json:"type"`
+	Policy policies.PolicyBlock `json:"policy"`
+}
+
+type CheckResponse struct {
+	Problems []string `json:"problems,omitempty"`
+}
+
+// ConflictRequest lints two policy blocks with the Conflict of their policy type
+type ConflictRequest struct {
+	Type string               `json:"type"`
+	A    policies.PolicyBlock `json:"a"`
+	B    policies.PolicyBlock `json:"b"`
+}
+
+type ConflictResponse struct {
+	Reason   string `json:"reason,omitempty"`
+	Conflict bool   `json:"conflict"`
+}
+
+// ExecuteRequest holds the payload of a resource policy executor. File is the
+// current content of the terraform file. SchemaFile is the schema file the host
+// reads the resource schemas from, if any, instead of the provider plugins
+type ExecuteRequest struct {
+	Type       string                        `json:"type"`
+	Policy     policies.PolicyBlock          `json:"policy"`
+	File       []byte                        `json:"file"`
+	FileName   string                        `json:"file_name"`
+	FilePath   string                        `json:"file_path"`
+	WorkingDir string                        `json:"working_dir"`
+	Flags      policies.PolicyExecutionFlags `json:"flags"`
+	SchemaFile string                        `json:"schema_file,omitempty"`
+}
+
+// ExecuteResponse holds the results of a resource policy executor and, when it
+// remediated the file, its rewritten content
+type ExecuteResponse struct {
+	Results []policies.PolicyResult `json:"results"`
+	File    []byte                  `json:"file,omitempty"`
+}
+
+type resourcePoliciesServer interface {
+	Schemas(ctx context.Context, request *SchemasRequest) (*SchemasResponse, error)
+	Check(ctx context.Context, request *CheckRequest) (*CheckResponse, error)
+	Conflict(ctx context.Context, request *ConflictRequest) (*ConflictResponse, error)
+	Execute(ctx context.Context, request *ExecuteRequest) (*ExecuteResponse, error)
+}
+
+// resourcePoliciesPlugin serves resource policies over gRPC only
+type resourcePoliciesPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	executors map[string]policies.ResourcePolicyExecutor
+}
+
+func (p *resourcePoliciesPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&serviceDesc, &server{executors: p.executors})
+	return nil
+}
+
+func (p *resourcePoliciesPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{conn: conn}, nil
+}
+
+// the messages are encoded as json rather than protobuf, so that they can be
+// declared as plain go types
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codec_name
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: service_name,
+	HandlerType: (*resourcePoliciesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		unaryMethod("Schemas", func(srv resourcePoliciesServer, ctx context.Context, request *SchemasRequest) (interface{}, error) {
+			return srv.Schemas(ctx, request)
+		}),
+		unaryMethod("Check", func(srv resourcePoliciesServer, ctx context.Context, request *CheckRequest) (interface{}, error) {
+			return srv.Check(ctx, request)
+		}),
+		unaryMethod("Conflict", func(srv resourcePoliciesServer, ctx context.Context, request *ConflictRequest) (interface{}, error) {
+			return srv.Conflict(ctx, request)
+		}),
+		unaryMethod("Execute", func(srv resourcePoliciesServer, ctx context.Context, request *ExecuteRequest) (interface{}, error) {
+			return srv.Execute(ctx, request)
+		}),
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+// unaryMethod declares a method of the service decoding its request as R
+func unaryMethod[R any](name string, call func(srv resourcePoliciesServer, ctx context.Context, request *R) (interface{}, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			request := new(R)
+			if err := dec(request); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+				return call(srv.(resourcePoliciesServer), ctx, request.(*R))
+			}
+			if interceptor == nil {
+				return handler(ctx, request)
+			}
+			return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + service_name + "/" + name}, handler)
+		},
+	}
+}
+
+type grpcClient struct {
+	conn *grpc.ClientConn
+}
+
+func (c *grpcClient) Schemas(ctx context.Context, request *SchemasRequest) (*SchemasResponse, error) {
+	response := new(SchemasResponse)
+	err := c.conn.Invoke(ctx, "/"+service_name+"/Schemas", request, response, grpc.CallContentSubtype(codec_name))
+	return response, err
+}
+
+func (c *grpcClient) Check(ctx context.Context, request *CheckRequest) (*CheckResponse, error) {
+	response := new(CheckResponse)
+	err := c.conn.Invoke(ctx, "/"+service_name+"/Check", request, response, grpc.CallContentSubtype(codec_name))
+	return response, err
+}
+
+func (c *grpcClient) Conflict(ctx context.Context, request *ConflictRequest) (*ConflictResponse, error) {
+	response := new(ConflictResponse)
+	err := c.conn.Invoke(ctx, "/"+service_name+"/Conflict", request, response, grpc.CallContentSubtype(codec_name))
+	return response, err
+}
+
+func (c *grpcClient) Execute(ctx context.Context, request *ExecuteRequest) (*ExecuteResponse, error) {
+	response := new(ExecuteResponse)
+	err := c.conn.Invoke(ctx, "/"+service_name+"/Execute", request, response, grpc.CallContentSubtype(codec_name))
+	return response, err
+}
@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clearbank/terrapolicy/internals/file"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"github.com/clearbank/terrapolicy/policies"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/zclconf/go-cty/cty"
+
+	. "github.com/onsi/gomega"
+)
+
+const pluginSource = `resource "azurerm_resource_group" "main" {
+  name = "main"
+}
+`
+
+type ownerTagPolicy struct{}
+
+func (p *ownerTagPolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{
+		Params: map[string]policies.ParamSchema{
+			"owner": {Type: policies.PARAM_STRING, Required: true},
+		},
+		Check: func(block policies.PolicyBlock) []string {
+			if owner := block.StringParam("owner"); owner != strings.ToLower(owner) {
+				return []string{"owner must be lower case"}
+			}
+			return nil
+		},
+		Conflict: func(a policies.PolicyBlock, b policies.PolicyBlock) (string, bool) {
+			if a.StringParam("owner") != b.StringParam("owner") {
+				return "contradicting owners", true
+			}
+			return "", false
+		},
+	}
+}
+
+func (p *ownerTagPolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	var results []policies.PolicyResult
+	for _, block := range payload.Hcl.Body().Blocks() {
+		result := policies.PolicyResult{ResourceType: block.Labels()[0], ResourceName: block.Labels()[1]}
+		if block.Body().GetAttribute("tags") == nil {
+			block.Body().SetAttributeValue("tags", cty.ObjectVal(map[string]cty.Value{"owner": cty.StringVal(payload.Policy.StringParam("owner"))}))
+			result.Outcome = policies.OUTCOME_REMEDIATE
+			result.Range, _ = payload.Source.BlockRange(block)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// headerPolicy rewrites the file with a header, then locates its blocks
+type headerPolicy struct{}
+
+func (p *headerPolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{}
+}
+
+func (p *headerPolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	if err := payload.Rewrite(append([]byte("# header\n"), payload.Hcl.Bytes()...)); err != nil {
+		return nil, err
+	}
+
+	var results []policies.PolicyResult
+	for _, block := range payload.Hcl.Body().Blocks() {
+		r, ok := payload.Source.BlockRange(block)
+		if !ok {
+			return nil, fmt.Errorf("cannot locate %v", block.Labels())
+		}
+		results = append(results, policies.PolicyResult{Outcome: policies.OUTCOME_REMEDIATE, Range: r})
+	}
+	return results, nil
+}
+
+// schemaPolicy reports the type of the name attribute in the schemas it receives
+type schemaPolicy struct{}
+
+func (p *schemaPolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{}
+}
+
+func (p *schemaPolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	schema, err := payload.Schemas.ResourceSchema(policies.ProviderRef{LocalName: "azurerm", Source: "registry.terraform.io/hashicorp/azurerm"}, "azurerm_resource_group")
+	if err != nil {
+		return nil, err
+	}
+	return []policies.PolicyResult{{Reason: schema.Attributes["name"].Type.FriendlyName()}}, nil
+}
+
+func TestPluginSchemaFile(t *testing.T) {
+	g := NewWithT(t)
+
+	client, _ := goplugin.TestPluginGRPCConn(t, goplugin.PluginSet{
+		plugin_name: &resourcePoliciesPlugin{executors: map[string]policies.ResourcePolicyExecutor{"schema_policy": &schemaPolicy{}}},
+	})
+	defer client.Close()
+
+	raw, err := client.Dispense(plugin_name)
+	g.Expect(err).To(BeNil())
+
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	g.Expect(os.WriteFile(path, []byte(`{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/hashicorp/azurerm": {
+      "resource_schemas": {
+        "azurerm_resource_group": {"block": {"attributes": {"name": {"type": "string", "required": true}}}}
+      }
+    }
+  }
+}`), 0644)).To(Succeed())
+
+	schemas, err := tfschema.LoadSchemaFile(path)
+	g.Expect(err).To(BeNil())
+
+	hcl, err := file.ParseHCL([]byte(pluginSource), "main.tf")
+	g.Expect(err).To(BeNil())
+
+	// the plugin reads the schema file of the host, without provider plugins
+	results, err := (&executor{service: raw.(resourcePoliciesServer), policyType: "schema_policy"}).Execute(policies.ResourcePolicyPayload{
+		Hcl:        hcl,
+		FilePath:   "main.tf",
+		WorkingDir: t.TempDir(),
+		Schemas:    schemas,
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(1))
+	g.Expect(results[0].Reason).To(Equal("string"))
+}
+
+func TestPlugin(t *testing.T) {
+	g := NewWithT(t)
+
+	client, _ := goplugin.TestPluginGRPCConn(t, goplugin.PluginSet{
+		plugin_name: &resourcePoliciesPlugin{executors: map[string]policies.ResourcePolicyExecutor{"owner_tag_policy": &ownerTagPolicy{}}},
+	})
+	defer client.Close()
+
+	raw, err := client.Dispense(plugin_name)
+	g.Expect(err).To(BeNil())
+
+	c := &Client{service: raw.(resourcePoliciesServer)}
+	executors, err := c.Executors()
+	g.Expect(err).To(BeNil())
+	g.Expect(executors).To(HaveKey("owner_tag_policy"))
+	g.Expect(executors["owner_tag_policy"].Schema().Params["owner"].Required).To(BeTrue())
+
+	hcl, err := file.ParseHCL([]byte(pluginSource), "main.tf")
+	g.Expect(err).To(BeNil())
+
+	var rewritten string
+	results, err := executors["owner_tag_policy"].Execute(policies.ResourcePolicyPayload{
+		Hcl:      hcl,
+		Policy:   policies.PolicyBlock{Type: "owner_tag_policy", Severity: policies.SEVERITY_LOW, Params: map[string]interface{}{"owner": "platform"}},
+		FilePath: "main.tf",
+		Rewrite: func(content []byte) error {
+			rewritten = string(content)
+			return nil
+		},
+	})
+
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(1))
+	g.Expect(results[0].Outcome).To(Equal(policies.OUTCOME_REMEDIATE))
+	g.Expect(results[0].Address()).To(Equal("azurerm_resource_group.main"))
+	g.Expect(results[0].Range.Start.Line).To(Equal(1))
+	g.Expect(rewritten).To(ContainSubstring(`owner = "platform"`))
+
+	_, err = (&executor{service: c.service, policyType: "unknown"}).Execute(policies.ResourcePolicyPayload{Hcl: hcl})
+	g.Expect(err).To(HaveOccurred())
+
+	// the checks of the plugin lint the policy blocks
+	schema := executors["owner_tag_policy"].Schema()
+	g.Expect(schema.Check).NotTo(BeNil())
+	g.Expect(schema.Check(policies.PolicyBlock{Params: map[string]interface{}{"owner": "Platform"}})).To(Equal([]string{"owner must be lower case"}))
+	g.Expect(schema.Check(policies.PolicyBlock{Params: map[string]interface{}{"owner": "platform"}})).To(BeEmpty())
+
+	g.Expect(schema.Conflict).NotTo(BeNil())
+	reason, conflict := schema.Conflict(
+		policies.PolicyBlock{Params: map[string]interface{}{"owner": "platform"}},
+		policies.PolicyBlock{Params: map[string]interface{}{"owner": "security"}})
+	g.Expect(conflict).To(BeTrue())
+	g.Expect(reason).To(Equal("contradicting owners"))
+}
+
+func TestPluginRewrite(t *testing.T) {
+	g := NewWithT(t)
+
+	client, _ := goplugin.TestPluginGRPCConn(t, goplugin.PluginSet{
+		plugin_name: &resourcePoliciesPlugin{executors: map[string]policies.ResourcePolicyExecutor{"header_policy": &headerPolicy{}}},
+	})
+	defer client.Close()
+
+	raw, err := client.Dispense(plugin_name)
+	g.Expect(err).To(BeNil())
+
+	c := &Client{service: raw.(resourcePoliciesServer)}
+	executors, err := c.Executors()
+	g.Expect(err).To(BeNil())
+	g.Expect(executors["header_policy"].Schema().Check).To(BeNil())
+
+	hcl, err := file.ParseHCL([]byte(pluginSource), "main.tf")
+	g.Expect(err).To(BeNil())
+
+	var rewritten string
+	results, err := executors["header_policy"].Execute(policies.ResourcePolicyPayload{
+		Hcl:      hcl,
+		FilePath: "main.tf",
+		Rewrite: func(content []byte) error {
+			rewritten = string(content)
+			return nil
+		},
+	})
+
+	// the block is located in the rewritten content, after the header
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(1))
+	g.Expect(results[0].Range.Start.Line).To(Equal(2))
+	g.Expect(rewritten).To(HavePrefix("# header\n"))
+}
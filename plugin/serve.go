@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/clearbank/terrapolicy/internals/file"
+	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"github.com/clearbank/terrapolicy/policies"
+	goplugin "github.com/hashicorp/go-plugin"
+)
+
+// Serve serves the executors of the given resource policy types. It must be
+// called from the main function of the plugin binary and does not return
+func Serve(executors map[string]policies.ResourcePolicyExecutor) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         goplugin.PluginSet{plugin_name: &resourcePoliciesPlugin{executors: executors}},
+		GRPCServer:      goplugin.DefaultGRPCServer,
+	})
+}
+
+type server struct {
+	executors map[string]policies.ResourcePolicyExecutor
+
+	// schemaFiles holds the schema files loaded, by path. Files are evaluated concurrently
+	schemaFilesLock sync.Mutex
+	schemaFiles     map[string]policies.SchemaSource
+}
+
+func (s *server) Schemas(ctx context.Context, request *SchemasRequest) (*SchemasResponse, error) {
+	response := &SchemasResponse{
+		Schemas:   make(map[string]map[string]policies.ParamSchema),
+		Checks:    make(map[string]bool),
+		Conflicts: make(map[string]bool),
+	}
+	for name, executor := range s.executors {
+		schema := executor.Schema()
+		response.Schemas[name] = schema.Params
+		response.Checks[name] = schema.Check != nil
+		response.Conflicts[name] = schema.Conflict != nil
+	}
+	return response, nil
+}
+
+func (s *server) Check(ctx context.Context, request *CheckRequest) (*CheckResponse, error) {
+	executor, ok := s.executors[request.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy type: %v", request.Type)
+	}
+
+	response := &CheckResponse{}
+	if check := executor.Schema().Check; check != nil {
+		response.Problems = check(request.Policy)
+	}
+	return response, nil
+}
+
+func (s *server) Conflict(ctx context.Context, request *ConflictRequest) (*ConflictResponse, error) {
+	executor, ok := s.executors[request.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy type: %v", request.Type)
+	}
+
+	response := &ConflictResponse{}
+	if conflict := executor.Schema().Conflict; conflict != nil {
+		response.Reason, response.Conflict = conflict(request.A, request.B)
+	}
+	return response, nil
+}
+
+func (s *server) Execute(ctx context.Context, request *ExecuteRequest) (*ExecuteResponse, error) {
+	executor, ok := s.executors[request.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy type: %v", request.Type)
+	}
+
+	hcl, err := file.ParseHCL(request.File, request.FilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err := terraform.ReadModuleProviders(filepath.Dir(request.FilePath))
+	if err != nil {
+		return nil, err
+	}
+
+	schemas, err := s.schemas(request)
+	if err != nil {
+		return nil, err
+	}
+
+	// a rewrite updates the file and its source index in place, so that the executor
+	// locates its following findings in the rewritten content
+	source := terraform.NewSourceIndex(request.FilePath, hcl)
+	results, err := executor.Execute(policies.ResourcePolicyPayload{
+		Hcl:        hcl,
+		Source:     source,
+		Policy:     request.Policy,
+		WorkingDir: request.WorkingDir,
+		FileName:   request.FileName,
+		FilePath:   request.FilePath,
+		Flags:      request.Flags,
+		Schemas:    schemas,
+		Providers:  providers,
+		Rewrite: func(content []byte) error {
+			rewritten, err := file.ParseHCL(content, request.FilePath)
+			if err != nil {
+				return err
+			}
+			*hcl, *source = *rewritten, *terraform.NewSourceIndex(request.FilePath, rewritten)
+			return nil
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ExecuteResponse{Results: results}
+	for _, result := range results {
+		if result.Outcome == policies.OUTCOME_REMEDIATE {
+			response.File = hcl.Bytes()
+			break
+		}
+	}
+
+	return response, nil
+}
+
+// schemas returns the schemas of the schema file of the host, loaded once, or
+// else of the provider plugins installed in the root module
+func (s *server) schemas(request *ExecuteRequest) (policies.SchemaSource, error) {
+	if request.SchemaFile == "" {
+		return tfschema.NewPluginSource(request.WorkingDir), nil
+	}
+
+	s.schemaFilesLock.Lock()
+	defer s.schemaFilesLock.Unlock()
+
+	if source, ok := s.schemaFiles[request.SchemaFile]; ok {
+		return source, nil
+	}
+
+	source, err := tfschema.LoadSchemaFile(request.SchemaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.schemaFiles == nil {
+		s.schemaFiles = make(map[string]policies.SchemaSource)
+	}
+	s.schemaFiles[request.SchemaFile] = source
+	return source, nil
+}
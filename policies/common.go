@@ -0,0 +1,206 @@
+// Package policies declares the policy file and the executors evaluating its
+// blocks. Executors of custom policy types implement ResourcePolicyExecutor or
+// ProviderPolicyExecutor and are registered with terrapolicy.RegisterResourcePolicy
+// or terrapolicy.RegisterProviderPolicy
+package policies
+
+import (
+	"fmt"
+
+	"github.com/clearbank/terrapolicy/internals/providers"
+	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+type Policy struct {
+	Extends     []string            `yaml:"extends,omitempty"`
+	Providers   []PolicyBlock       `yaml:"providers,omitempty"`
+	Resources   []PolicyBlock       `yaml:"resources,omitempty"`
+	Remediation RemediationSettings `yaml:"remediation,omitempty"`
+	Waivers     []Waiver            `yaml:"waivers,omitempty"`
+	Plugins     []PluginConfig      `yaml:"plugins,omitempty"`
+}
+
+// PluginConfig declares a plugin binary serving policy types. A relative path
+// is relative to the policy file declaring the plugin
+type PluginConfig struct {
+	Name string `yaml:"name,omitempty"`
+	Path string `yaml:"path"`
+}
+
+func (c PluginConfig) Describe() string {
+	if c.Name == "" {
+		return c.Path
+	}
+	return fmt.Sprintf("`%v` (%v)", c.Name, c.Path)
+}
+
+type RemediationSettings struct {
+	WriteStrategy string `yaml:"write_strategy,omitempty"`
+	Out           string `yaml:"out,omitempty"`
+}
+
+type PolicyBlock struct {
+	Id                  string                 `yaml:"id,omitempty"`
+	Name                string                 `yaml:"name,omitempty"`
+	Description         string                 `yaml:"description,omitempty"`
+	RemediationGuidance string                 `yaml:"remediation_guidance,omitempty"`
+	Labels              map[string]string      `yaml:"labels,omitempty"`
+	Type                string                 `yaml:"type"`
+	Severity            Severity               `yaml:"severity,omitempty"`
+	Scope               Scope                  `yaml:"scope,omitempty"`
+	Params              map[string]interface{} `yaml:"params"`
+}
+
+// Describe identifies the policy block in logs
+func (b PolicyBlock) Describe() string {
+	if b.Id == "" {
+		return fmt.Sprintf("`%v`", b.Type)
+	}
+	return fmt.Sprintf("`%v` (%v)", b.Id, b.Type)
+}
+
+type PolicyOutcome uint64
+
+const (
+	OUTCOME_SUCCESS PolicyOutcome = iota
+	OUTCOME_FAIL
+	OUTCOME_REMEDIATE
+	OUTCOME_SUPPRESSED
+	OUTCOME_WAIVED
+	// OUTCOME_WARN reports what a policy could not evaluate, without failing
+	OUTCOME_WARN
+)
+
+var outcomeNames = map[PolicyOutcome]string{
+	OUTCOME_SUCCESS:    "success",
+	OUTCOME_FAIL:       "fail",
+	OUTCOME_REMEDIATE:  "remediate",
+	OUTCOME_SUPPRESSED: "suppressed",
+	OUTCOME_WAIVED:     "waived",
+	OUTCOME_WARN:       "warn",
+}
+
+func (o PolicyOutcome) String() string {
+	if name, ok := outcomeNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (o PolicyOutcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *PolicyOutcome) UnmarshalText(text []byte) error {
+	for outcome, name := range outcomeNames {
+		if name == string(text) {
+			*o = outcome
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome: %s", text)
+}
+
+type PolicyResult struct {
+	Outcome      PolicyOutcome
+	Reason       string
+	ResourceType string
+	ResourceName string
+	Range        hcl.Range
+	Remediations []Remediation
+}
+
+// Remediation describes a value written by a policy. Text replaces Range in the
+// original file; an empty Range marks an insertion at its start
+type Remediation struct {
+	Attribute string
+	Value     interface{}
+	Range     hcl.Range
+	Text      string
+}
+
+func (r PolicyResult) Address() string {
+	return r.ResourceType + "." + r.ResourceName
+}
+
+const (
+	SECTION_PROVIDERS = "providers"
+	SECTION_RESOURCES = "resources"
+)
+
+type Finding struct {
+	Policy    PolicyBlock
+	PolicyRef string
+	FilePath  string
+	Result    PolicyResult
+}
+
+// Ref identifies a policy block by its position in the policy file, e.g. resources[2]
+func Ref(section string, index int) string {
+	return fmt.Sprintf("%v[%d]", section, index)
+}
+
+// SourceIndex locates the blocks and attributes of a terraform file in its original source
+type SourceIndex = terraform.SourceIndex
+
+// ProviderVersion is the version of a provider installed in the root module
+type ProviderVersion = providers.Version
+
+// ModuleMetadata describes a module of the root module, as listed in modules.json
+type ModuleMetadata = terraform.ModuleMetadata
+
+// ModuleProviders holds the providers required by a module, to resolve the provider of its resources
+type ModuleProviders = terraform.ModuleProviders
+
+// ProviderRef identifies the provider of a resource
+type ProviderRef = terraform.ProviderRef
+
+// SchemaSource provides the schemas of resource types, from the provider plugins
+// of the root module or from a file output by `terraform providers schema -json`
+type SchemaSource = tfschema.SchemaSource
+
+type PolicyExecutionFlags struct {
+	Strict               bool
+	DisallowSuppressions bool
+}
+
+// ResourcePolicyPayload is the input of a resource policy executor. Executors
+// remediate by modifying Hcl, or by replacing the whole content of the file with
+// Rewrite. Rewrite updates Hcl and Source in place. Source locates the tokens of
+// the file as it was read, or as it was last rewritten: once a policy rewrites the
+// file, the ranges of its following findings and of the findings of the policies
+// evaluated after it refer to the remediated content. Library callers
+// may leave Source and Schemas nil, built-in executors then default them
+type ResourcePolicyPayload struct {
+	Hcl        *hclwrite.File
+	Source     *SourceIndex
+	Policy     PolicyBlock
+	WorkingDir string
+	FileName   string
+	FilePath   string
+	Flags      PolicyExecutionFlags
+	Rewrite    func(content []byte) error
+	Schemas    SchemaSource
+	Providers  *ModuleProviders
+}
+
+type ProviderPolicyPayload struct {
+	Policy           PolicyBlock
+	WorkingDir       string
+	Flags            PolicyExecutionFlags
+	CurrentProviders map[string]ProviderVersion
+}
+
+type ResourcePolicyExecutor interface {
+	Execute(payload ResourcePolicyPayload) ([]PolicyResult, error)
+	Schema() PolicySchema
+}
+
+type ProviderPolicyExecutor interface {
+	Execute(payload ProviderPolicyPayload) ([]PolicyResult, error)
+	Schema() PolicySchema
+}
@@ -0,0 +1,397 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/clearbank/terrapolicy/internals/file"
+	"github.com/clearbank/terrapolicy/internals/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an issue of a policy file, located by its yaml line and column
+type Problem struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%v:%d:%d: %v", p.File, p.Line, p.Column, p.Message)
+}
+
+type linter struct {
+	schemas  Schemas
+	file     string
+	problems []Problem
+}
+
+// resolvedPolicy is a policy merged with the policies it extends, along with
+// the location of each of its blocks
+type resolvedPolicy struct {
+	policy    Policy
+	providers []blockSource
+	resources []blockSource
+}
+
+type blockSource struct {
+	file  string
+	node  *yaml.Node
+	valid bool
+}
+
+// Lint parses the policy at path, resolves the policies it extends and reports
+// every problem found against the schemas of the policy types. An error is only
+// returned if the file cannot be read or is not a valid policy document
+func Lint(path string, schemas Schemas) (Policy, []Problem, error) {
+	l := linter{schemas: schemas}
+	resolved, err := l.lintFile(path, nil)
+
+	if err != nil {
+		return resolved.policy, nil, err
+	}
+
+	l.lintConflicts(SECTION_PROVIDERS, resolved.policy.Providers, resolved.providers, schemas.Providers)
+	l.lintConflicts(SECTION_RESOURCES, resolved.policy.Resources, resolved.resources, schemas.Resources)
+
+	resolved.policy.Extends = nil
+	return resolved.policy, l.problems, nil
+}
+
+// lintFile lints the policy at path merged over the policies it extends. stack
+// holds the files extending it, to detect cycles
+func (l *linter) lintFile(path string, stack []string) (resolvedPolicy, error) {
+	resolved := resolvedPolicy{}
+	data, err := file.ReadFile(path)
+
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return resolved, errors.New("config_read")
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		log.Printf("[ERROR] %v", err)
+		return resolved, errors.New("unmarshal_error")
+	}
+
+	if len(document.Content) == 0 {
+		return resolved, nil
+	}
+
+	policy := Policy{}
+	if err := document.Decode(&policy); err != nil {
+		log.Printf("[ERROR] %v", err)
+		return resolved, errors.New("unmarshal_error")
+	}
+
+	for i, plugin := range policy.Plugins {
+		if plugin.Path != "" && !filepath.IsAbs(plugin.Path) {
+			policy.Plugins[i].Path = filepath.Join(filepath.Dir(path), plugin.Path)
+		}
+	}
+
+	root := document.Content[0]
+	absPath, _ := filepath.Abs(path)
+	stack = append(stack, absPath)
+
+	_, extendsNode := mappingEntry(root, "extends")
+	for i, extended := range policy.Extends {
+		if !filepath.IsAbs(extended) {
+			extended = filepath.Join(filepath.Dir(path), extended)
+		}
+
+		l.file = path
+		if absExtended, _ := filepath.Abs(extended); utils.Contains(stack, absExtended) {
+			l.report(extendsNode.Content[i], "cyclic extends of %v", extended)
+			continue
+		}
+
+		parent, err := l.lintFile(extended, stack)
+		l.file = path
+		if err != nil {
+			l.report(extendsNode.Content[i], "cannot extend %v: %v", extended, err)
+			continue
+		}
+
+		resolved = merge(resolved, parent)
+	}
+
+	l.file = path
+	return merge(resolved, l.lintPolicy(root, policy)), nil
+}
+
+// merge appends the blocks of overlay to base. A block with the id of a block
+// of base replaces it
+func merge(base resolvedPolicy, overlay resolvedPolicy) resolvedPolicy {
+	base.policy.Providers, base.providers = mergeBlocks(base.policy.Providers, base.providers, overlay.policy.Providers, overlay.providers)
+	base.policy.Resources, base.resources = mergeBlocks(base.policy.Resources, base.resources, overlay.policy.Resources, overlay.resources)
+	base.policy.Waivers = append(base.policy.Waivers, overlay.policy.Waivers...)
+	base.policy.Plugins = append(base.policy.Plugins, overlay.policy.Plugins...)
+	base.policy.Extends = overlay.policy.Extends
+
+	if overlay.policy.Remediation.WriteStrategy != "" {
+		base.policy.Remediation.WriteStrategy = overlay.policy.Remediation.WriteStrategy
+	}
+	if overlay.policy.Remediation.Out != "" {
+		base.policy.Remediation.Out = overlay.policy.Remediation.Out
+	}
+
+	return base
+}
+
+func mergeBlocks(blocks []PolicyBlock, sources []blockSource, overlay []PolicyBlock, overlaySources []blockSource) ([]PolicyBlock, []blockSource) {
+	blocks, sources = append([]PolicyBlock{}, blocks...), append([]blockSource{}, sources...)
+
+	for i, block := range overlay {
+		index := -1
+		for j := range blocks {
+			if block.Id != "" && blocks[j].Id == block.Id {
+				index = j
+			}
+		}
+
+		if index >= 0 {
+			log.Printf("[DEBUG] policy %v overrides the extended policy", block.Describe())
+			blocks[index], sources[index] = block, overlaySources[i]
+		} else {
+			blocks, sources = append(blocks, block), append(sources, overlaySources[i])
+		}
+	}
+
+	return blocks, sources
+}
+
+func (l *linter) report(node *yaml.Node, format string, args ...interface{}) {
+	l.problems = append(l.problems, Problem{File: l.file, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) lintPolicy(node *yaml.Node, policy Policy) resolvedPolicy {
+	l.lintFields(node, "", reflect.TypeOf(policy))
+
+	resolved := resolvedPolicy{policy: policy}
+	ids := make(map[string]string)
+	sections := []struct {
+		name    string
+		blocks  []PolicyBlock
+		sources *[]blockSource
+		schemas map[string]PolicySchema
+	}{
+		{SECTION_PROVIDERS, policy.Providers, &resolved.providers, l.schemas.Providers},
+		{SECTION_RESOURCES, policy.Resources, &resolved.resources, l.schemas.Resources},
+	}
+
+	for _, section := range sections {
+		_, sequence := mappingEntry(node, section.name)
+		if sequence == nil || sequence.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for i, block := range section.blocks {
+			blockNode := sequence.Content[i]
+			ref := Ref(section.name, i)
+
+			if block.Id != "" {
+				_, idNode := mappingEntry(blockNode, "id")
+				if previous, ok := ids[block.Id]; ok {
+					l.report(idNode, "%v: policy id `%v` is already declared by %v", ref, block.Id, previous)
+				} else {
+					ids[block.Id] = ref
+				}
+			}
+
+			if _, scopeNode := mappingEntry(blockNode, "scope"); scopeNode != nil {
+				if section.name != SECTION_RESOURCES {
+					l.report(scopeNode, "%v: scope is only supported by resource policies", ref)
+				} else if err := block.Scope.Validate(); err != nil {
+					l.report(scopeNode, "%v: invalid scope: %v", ref, err)
+				}
+			}
+
+			valid := l.lintBlock(blockNode, ref, block, section.schemas)
+			*section.sources = append(*section.sources, blockSource{file: l.file, node: blockNode, valid: valid})
+		}
+	}
+
+	if _, sequence := mappingEntry(node, "plugins"); sequence != nil && sequence.Kind == yaml.SequenceNode {
+		for i, plugin := range policy.Plugins {
+			if plugin.Path == "" {
+				l.report(sequence.Content[i], "plugins[%d]: path is required", i)
+			}
+		}
+	}
+
+	if _, sequence := mappingEntry(node, "waivers"); sequence != nil && sequence.Kind == yaml.SequenceNode {
+		for i, waiver := range policy.Waivers {
+			if err := validateWaiver(waiver); err != nil {
+				l.report(sequence.Content[i], "waivers[%d]: %v", i, err)
+			}
+		}
+	}
+
+	return resolved
+}
+
+// lintConflicts reports the contradicting blocks of a section of the resolved policy
+func (l *linter) lintConflicts(section string, blocks []PolicyBlock, sources []blockSource, schemas map[string]PolicySchema) {
+	for i, block := range blocks {
+		schema := schemas[block.Type]
+		if !sources[i].valid || schema.Conflict == nil {
+			continue
+		}
+
+		for j, other := range blocks[:i] {
+			if !sources[j].valid || other.Type != block.Type {
+				continue
+			}
+			if reason, ok := schema.Conflict(other, block); ok {
+				l.file = sources[i].file
+				l.report(sources[i].node, "%v contradicts %v: %v", describeSource(section, i, block), describeSource(section, j, other), reason)
+			}
+		}
+	}
+}
+
+// describeSource identifies a block of the resolved policy by its id, or its position
+func describeSource(section string, index int, block PolicyBlock) string {
+	if block.Id != "" {
+		return fmt.Sprintf("`%v`", block.Id)
+	}
+	return Ref(section, index)
+}
+
+// lintBlock reports the problems of a policy block and whether its parameters are valid
+func (l *linter) lintBlock(node *yaml.Node, ref string, block PolicyBlock, schemas map[string]PolicySchema) bool {
+	l.lintFields(node, ref+": ", reflect.TypeOf(block))
+
+	_, typeNode := mappingEntry(node, "type")
+	if typeNode == nil {
+		l.report(node, "%v: missing policy type", ref)
+		return false
+	}
+
+	schema, ok := schemas[block.Type]
+	if !ok {
+		l.report(typeNode, "%v: unknown policy type `%v`, expected one of: %v", ref, block.Type, strings.Join(utils.SortedKeys(schemas), ","))
+		return false
+	}
+
+	_, paramsNode := mappingEntry(node, "params")
+	if paramsNode == nil {
+		paramsNode = &yaml.Node{Kind: yaml.MappingNode, Line: node.Line, Column: node.Column}
+	}
+
+	problems := len(l.problems)
+	if paramsNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(paramsNode.Content); i += 2 {
+			key, value := paramsNode.Content[i], paramsNode.Content[i+1]
+			param, ok := schema.Params[key.Value]
+			if !ok {
+				l.report(key, "%v: unknown param `%v` for policy type `%v`", ref, key.Value, block.Type)
+				continue
+			}
+			l.lintParam(value, ref, key.Value, param)
+		}
+	}
+
+	for _, name := range utils.SortedKeys(schema.Params) {
+		if _, value := mappingEntry(paramsNode, name); schema.Params[name].Required && (value == nil || value.ShortTag() == "!!null") {
+			l.report(paramsNode, "%v: missing required param `%v`", ref, name)
+		}
+	}
+
+	if len(l.problems) > problems {
+		return false
+	}
+
+	if schema.Check != nil {
+		for _, message := range schema.Check(block) {
+			l.report(paramsNode, "%v: %v", ref, message)
+		}
+	}
+
+	return len(l.problems) == problems
+}
+
+func (l *linter) lintParam(node *yaml.Node, ref string, name string, param ParamSchema) {
+	isString := func(n *yaml.Node) bool {
+		return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!str"
+	}
+
+	switch param.Type {
+	case PARAM_STRING:
+		if !isString(node) {
+			l.report(node, "%v: param `%v` must be a string", ref, name)
+			return
+		}
+	case PARAM_STRING_LIST:
+		if node.Kind == yaml.SequenceNode {
+			for _, item := range node.Content {
+				if !isString(item) {
+					l.report(item, "%v: param `%v` must be a string or a list of strings", ref, name)
+				}
+			}
+			return
+		}
+		if !isString(node) {
+			l.report(node, "%v: param `%v` must be a string or a list of strings", ref, name)
+			return
+		}
+	case PARAM_BOOL:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			l.report(node, "%v: param `%v` must be a boolean", ref, name)
+		}
+		return
+	default:
+		return
+	}
+
+	if len(param.Values) == 0 {
+		return
+	}
+
+	for _, value := range param.Values {
+		if node.Value == value {
+			return
+		}
+	}
+	l.report(node, "%v: unknown value `%v` for param `%v`, expected one of: %v", ref, node.Value, name, strings.Join(param.Values, ","))
+}
+
+// lintFields reports the keys of a mapping node that are not fields of t
+func (l *linter) lintFields(node *yaml.Node, prefix string, t reflect.Type) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		fields[name] = true
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if key := node.Content[i]; !fields[key.Value] {
+			l.report(key, "%vunknown field `%v`", prefix, key.Value)
+		}
+	}
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,135 @@
+package policies
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+var testSchemas = Schemas{
+	Providers: map[string]PolicySchema{
+		"version_policy": {
+			Params: map[string]ParamSchema{
+				"provider": {Type: PARAM_STRING, Required: true},
+				"value":    {Type: PARAM_STRING_LIST, Required: true},
+				"strategy": {Type: PARAM_STRING, Required: true, Values: []string{"minimum_version", "exclude"}},
+			},
+		},
+		"lock_file_policy": {
+			Params: map[string]ParamSchema{
+				"platforms": {Type: PARAM_STRING_LIST, Required: true},
+				"registry":  {Type: PARAM_BOOL},
+			},
+		},
+	},
+	Resources: map[string]PolicySchema{
+		"attributes_policy": {
+			Params: map[string]ParamSchema{
+				"resource":  {Type: PARAM_STRING, Required: true},
+				"attribute": {Type: PARAM_STRING, Required: true},
+				"value":     {Type: PARAM_ANY},
+				"strategy":  {Type: PARAM_STRING, Required: true, Values: []string{"fail_if_missing", "fail_if_set", "set_if_missing"}},
+			},
+			Check: func(block PolicyBlock) []string {
+				if block.StringParam("strategy") == "set_if_missing" && block.Params["value"] == nil {
+					return []string{"strategy `set_if_missing` requires param `value`"}
+				}
+				return nil
+			},
+			Conflict: func(a PolicyBlock, b PolicyBlock) (string, bool) {
+				return "same attribute", a.StringParam("attribute") == b.StringParam("attribute")
+			},
+		},
+	},
+}
+
+func TestLint(t *testing.T) {
+	g := NewWithT(t)
+
+	path := writePolicy(t, `
+providers:
+  - type: version_policy
+    params:
+      provider: registry.terraform.io/hashicorp/azurerm
+      value: 3.44
+      strategy: excluded
+resources:
+  - type: attribute_policy
+    params:
+      resource: azurerm_storage_account
+  - type: attributes_policy
+    params:
+      resource: azurerm_storage_account
+      strategy: set_if_missing
+      attributes: min_tls_version
+  - type: attributes_policy
+    serverity: low
+    params:
+      resource: azurerm_application_insights
+      attribute: workspace_id
+      strategy: fail_if_missing
+  - type: attributes_policy
+    params:
+      resource: azurerm_application_insights
+      attribute: workspace_id
+      strategy: fail_if_set
+`)
+
+	_, problems, err := Lint(path, testSchemas)
+	g.Expect(err).To(BeNil())
+
+	var messages []string
+	for _, problem := range problems {
+		messages = append(messages, fmt.Sprintf("%d:%d: %v", problem.Line, problem.Column, problem.Message))
+	}
+
+	g.Expect(messages).To(Equal([]string{
+		"6:14: providers[0]: param `value` must be a string or a list of strings",
+		"7:17: providers[0]: unknown value `excluded` for param `strategy`, expected one of: minimum_version,exclude",
+		"9:11: resources[0]: unknown policy type `attribute_policy`, expected one of: attributes_policy",
+		"16:7: resources[1]: unknown param `attributes` for policy type `attributes_policy`",
+		"14:7: resources[1]: missing required param `attribute`",
+		"18:5: resources[2]: unknown field `serverity`",
+		"23:5: resources[3] contradicts resources[2]: same attribute",
+	}))
+}
+
+func TestLintCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	path := writePolicy(t, `
+resources:
+  - type: attributes_policy
+    params:
+      resource: azurerm_storage_account
+      attribute: min_tls_version
+      strategy: set_if_missing
+`)
+
+	_, problems, err := Lint(path, testSchemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(problems).To(Equal([]Problem{{File: path, Line: 5, Column: 7, Message: "resources[0]: strategy `set_if_missing` requires param `value`"}}))
+}
+
+func TestLintBoolParam(t *testing.T) {
+	g := NewWithT(t)
+
+	path := writePolicy(t, `
+providers:
+  - type: lock_file_policy
+    params:
+      platforms: linux_amd64
+      registry: true
+  - type: lock_file_policy
+    params:
+      platforms: linux_amd64
+      registry: "yes"
+`)
+
+	policy, problems, err := Lint(path, testSchemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(problems).To(Equal([]Problem{{File: path, Line: 10, Column: 17, Message: "providers[1]: param `registry` must be a boolean"}}))
+	g.Expect(policy.Providers[0].BoolParam("registry")).To(BeTrue())
+	g.Expect(policy.Providers[1].BoolParam("registry")).To(BeFalse())
+}
@@ -0,0 +1,26 @@
+package policies
+
+import (
+	"errors"
+	"log"
+)
+
+// Parse parses the policy at path, merged with the policies it extends, and
+// fails if any problem is found against the schemas of the policy types
+func Parse(path string, schemas Schemas) (Policy, error) {
+	policy, problems, err := Lint(path, schemas)
+
+	if err != nil {
+		return policy, err
+	}
+
+	for _, problem := range problems {
+		log.Printf("[ERROR] %v", problem)
+	}
+
+	if len(problems) > 0 {
+		return policy, errors.New("invalid_policy")
+	}
+
+	return policy, nil
+}
@@ -0,0 +1,153 @@
+package policies
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseDuplicateIds(t *testing.T) {
+	g := NewWithT(t)
+
+	path := writePolicy(t, `
+providers:
+  - id: provider-version
+    type: version_policy
+    params:
+      provider: registry.terraform.io/hashicorp/azurerm
+      value: "3.44"
+      strategy: exclude
+resources:
+  - id: provider-version
+    type: attributes_policy
+    params:
+      resource: azurerm_application_insights
+      attribute: workspace_id
+      strategy: fail_if_set
+`)
+
+	_, err := Parse(path, testSchemas)
+	g.Expect(err).To(MatchError("invalid_policy"))
+
+	_, problems, err := Lint(path, testSchemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(problems).To(Equal([]Problem{{File: path, Line: 10, Column: 9, Message: "resources[0]: policy id `provider-version` is already declared by providers[0]"}}))
+}
+
+func TestParseMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	path := writePolicy(t, `
+resources:
+  - id: insights-workspace
+    name: Application insights are workspace based
+    description: Classic application insights are retired
+    remediation_guidance: Set workspace_id
+    labels:
+      team: platform
+    type: attributes_policy
+    severity: low
+    params:
+      resource: azurerm_application_insights
+      attribute: workspace_id
+      strategy: fail_if_missing
+`)
+
+	policy, err := Parse(path, testSchemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(policy.Resources).To(HaveLen(1))
+
+	block := policy.Resources[0]
+	g.Expect(block.Id).To(Equal("insights-workspace"))
+	g.Expect(block.Name).To(Equal("Application insights are workspace based"))
+	g.Expect(block.Description).To(Equal("Classic application insights are retired"))
+	g.Expect(block.RemediationGuidance).To(Equal("Set workspace_id"))
+	g.Expect(block.Labels).To(Equal(map[string]string{"team": "platform"}))
+	g.Expect(block.GetSeverity()).To(Equal(SEVERITY_LOW))
+}
+
+func writePolicy(t *testing.T, content string) string {
+	return writeFile(t, t.TempDir(), ".terrapolicy.yaml", content)
+}
+
+func TestParseExtends(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	writeFile(t, dir, "baseline.yaml", `
+resources:
+  - id: insights-workspace
+    type: attributes_policy
+    severity: low
+    params:
+      resource: azurerm_application_insights
+      attribute: workspace_id
+      strategy: fail_if_missing
+  - id: storage-tls
+    type: attributes_policy
+    params:
+      resource: azurerm_storage_account
+      attribute: min_tls_version
+      strategy: fail_if_missing
+`)
+	path := writeFile(t, dir, "team/.terrapolicy.yaml", `
+extends:
+  - ../baseline.yaml
+resources:
+  - id: insights-workspace
+    type: attributes_policy
+    severity: critical
+    params:
+      resource: azurerm_application_insights
+      attribute: workspace_id
+      strategy: fail_if_missing
+  - id: storage-replication
+    type: attributes_policy
+    params:
+      resource: azurerm_storage_account
+      attribute: account_replication_type
+      strategy: fail_if_missing
+`)
+
+	policy, err := Parse(path, testSchemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(policy.Extends).To(BeNil())
+
+	var ids []string
+	for _, block := range policy.Resources {
+		ids = append(ids, block.Id)
+	}
+	g.Expect(ids).To(Equal([]string{"insights-workspace", "storage-tls", "storage-replication"}))
+	g.Expect(policy.Resources[0].GetSeverity()).To(Equal(SEVERITY_CRITICAL))
+}
+
+func TestParseExtendsCycle(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	writeFile(t, dir, "a.yaml", `
+extends:
+  - b.yaml
+`)
+	path := writeFile(t, dir, "b.yaml", `
+extends:
+  - a.yaml
+`)
+
+	_, problems, err := Lint(path, testSchemas)
+	g.Expect(err).To(BeNil())
+	g.Expect(problems).To(Equal([]Problem{{File: filepath.Join(dir, "a.yaml"), Line: 3, Column: 5, Message: "cyclic extends of " + filepath.Join(dir, "b.yaml")}}))
+}
+
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,175 @@
+package provider_policies
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/policies"
+)
+
+type LockFilePolicy struct {
+	// Client queries the registries when the registry param is set, defaults to a client with a timeout
+	Client *http.Client
+}
+
+var platform_pattern = regexp.MustCompile(`^[a-z0-9]+_[a-z0-9]+$`)
+
+func (s *LockFilePolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{
+		Params: map[string]policies.ParamSchema{
+			"platforms": {Type: policies.PARAM_STRING_LIST, Required: true, Description: "the platforms every provider must have hashes for, e.g. linux_amd64"},
+			"registry":  {Type: policies.PARAM_BOOL, Description: "looks up the checksums of the packages not installed in the registry of the provider"},
+		},
+		Check: checkPlatforms,
+	}
+}
+
+func checkPlatforms(policy policies.PolicyBlock) []string {
+	platforms, err := stringValues(policy.Params["platforms"])
+	if err != nil {
+		return nil // reported by the param type
+	}
+
+	var problems []string
+	for _, platform := range platforms {
+		if !platform_pattern.MatchString(platform) {
+			problems = append(problems, fmt.Sprintf("invalid platform %v, expected <os>_<arch>", platform))
+		}
+	}
+	return problems
+}
+
+// Execute checks the lock file of the root module against the configuration of
+// the root module and its modules. The lock file does not record the platform of
+// its hashes, so the hash of the package of every platform is looked up in it:
+// the h1: hash of the package installed by terraform init or, with the registry
+// param, the zh: checksum the registry publishes. A platform that cannot be
+// looked up, not installed or with the registry unreachable, is not verified and
+// only warned about
+func (s *LockFilePolicy) Execute(payload policies.ProviderPolicyPayload) ([]policies.PolicyResult, error) {
+	platforms, err := stringValues(payload.Policy.Params["platforms"])
+	if err != nil {
+		return nil, err
+	}
+
+	required, referenced, err := configuredProviders(payload.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := terraform.ReadLockFile(payload.WorkingDir)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := &terraform.PackageHashes{RootDir: payload.WorkingDir, Registry: payload.Policy.BoolParam("registry"), Client: s.Client}
+	problems := make(map[string][]string)
+	warnings := make(map[string][]string)
+	lockedAddresses := make(map[string]bool)
+
+	for _, provider := range locked {
+		lockedAddresses[provider.Address] = true
+		problems[provider.Address] = nil
+
+		var missing []string
+		for _, platform := range platforms {
+			hash, err := hashes.Hash(provider, platform)
+			switch {
+			case errors.Is(err, terraform.ErrNotReleased):
+				problems[provider.Address] = append(problems[provider.Address], fmt.Sprintf("Not released for %v", platform))
+			case err != nil:
+				warnings[provider.Address] = append(warnings[provider.Address], fmt.Sprintf("Cannot verify the hash of %v: %v", platform, err))
+			case !hasHash(provider, hash):
+				missing = append(missing, platform)
+			}
+		}
+
+		if len(missing) > 0 {
+			problems[provider.Address] = append(problems[provider.Address], fmt.Sprintf(
+				"Missing the hash of %v, run terraform providers lock -platform=%v",
+				strings.Join(missing, ", "), strings.Join(platforms, " -platform=")))
+		}
+
+		if !referenced[provider.Address] {
+			problems[provider.Address] = append(problems[provider.Address], "Locked but not referenced by any configuration")
+		}
+	}
+
+	for address := range required {
+		if !lockedAddresses[address] {
+			problems[address] = append(problems[address], "Required but missing from the lock file")
+		}
+	}
+
+	addresses := make([]string, 0, len(problems))
+	for address := range problems {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	var results []policies.PolicyResult
+	for _, address := range addresses {
+		result := policies.PolicyResult{ResourceType: "provider", ResourceName: address}
+		if len(problems[address]) > 0 {
+			result.Outcome = policies.OUTCOME_FAIL
+			result.Reason = strings.Join(append(problems[address], warnings[address]...), "; ")
+		} else if len(warnings[address]) > 0 {
+			result.Outcome = policies.OUTCOME_WARN
+			result.Reason = strings.Join(warnings[address], "; ")
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// configuredProviders returns the source addresses of the providers required and
+// referenced by the root module and its modules
+func configuredProviders(dir string) (map[string]bool, map[string]bool, error) {
+	tfFiles, err := terraform.GetTerraformFiles(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	required, referenced := make(map[string]bool), make(map[string]bool)
+	seen := make(map[string]bool)
+
+	for _, tfFile := range tfFiles {
+		moduleDir := filepath.Dir(tfFile.Path)
+		if seen[moduleDir] {
+			continue
+		}
+		seen[moduleDir] = true
+
+		moduleProviders, err := terraform.ReadModuleProviders(moduleDir)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, provider := range moduleProviders.Required {
+			required[provider.Source] = true
+		}
+		for _, source := range moduleProviders.Referenced {
+			referenced[source] = true
+		}
+	}
+
+	log.Printf("[DEBUG] providers referenced by the configuration: %v", len(referenced))
+	return required, referenced, nil
+}
+
+func hasHash(provider terraform.LockedProvider, hash string) bool {
+	for _, locked := range provider.Hashes {
+		if locked == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package provider_policies
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clearbank/terrapolicy/policies"
+
+	. "github.com/onsi/gomega"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+const testLockFileConfiguration = `
+terraform {
+  required_providers {
+    azurerm = {
+      source = "hashicorp/azurerm"
+    }
+    tls = {
+      source = "hashicorp/tls"
+    }
+  }
+}
+
+resource "random_string" "default" {}
+`
+
+const testLockFile = `
+provider "registry.terraform.io/hashicorp/azurerm" {
+  version = "3.44.0"
+  hashes = [
+    "%v",
+    "%v",
+    "zh:release",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/random" {
+  version = "3.4.3"
+  hashes = [
+    "%v",
+    "zh:release",
+  ]
+}
+
+provider "registry.terraform.io/hashicorp/google" {
+  version = "4.50.0"
+  hashes = [
+    "%v",
+    "%v",
+  ]
+}
+`
+
+// testRegistry publishes the checksums of the provider packages, by
+// <namespace>/<type>/<version>/<os>_<arch>, as the registry of every host
+type testRegistry struct {
+	server   *httptest.Server
+	packages map[string]string
+}
+
+func newTestRegistry(t *testing.T, packages map[string]string) *testRegistry {
+	registry := &testRegistry{packages: packages}
+	registry.server = httptest.NewTLSServer(http.HandlerFunc(registry.serve))
+	t.Cleanup(registry.server.Close)
+	return registry
+}
+
+func (r *testRegistry) serve(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	switch {
+	case path == "/.well-known/terraform.json":
+		fmt.Fprint(w, `{"providers.v1": "/v1/providers/"}`)
+	case strings.HasPrefix(path, "/v1/providers/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/v1/providers/"), "/")
+		platform := parts[4] + "_" + parts[5]
+		if _, ok := r.packages[strings.Join(append(parts[:3:3], platform), "/")]; !ok {
+			http.NotFound(w, req)
+			return
+		}
+		fmt.Fprintf(w, `{"filename": "%v", "shasums_url": "/shasums/%v/%v/%v/SHA256SUMS"}`,
+			packageFileName(parts[1], parts[2], platform), parts[0], parts[1], parts[2])
+	case strings.HasPrefix(path, "/shasums/"):
+		release := strings.TrimSuffix(strings.TrimPrefix(path, "/shasums/"), "/SHA256SUMS")
+		parts := strings.Split(release, "/")
+		for key, content := range r.packages {
+			if platform, ok := strings.CutPrefix(key, release+"/"); ok {
+				fmt.Fprintf(w, "%x  %v\n", sha256.Sum256([]byte(content)), packageFileName(parts[1], parts[2], platform))
+			}
+		}
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// Client sends the requests to any host to the registry
+func (r *testRegistry) Client() *http.Client {
+	transport := r.server.Client().Transport
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req.URL.Host = r.server.Listener.Addr().String()
+		return transport.RoundTrip(req)
+	})}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func packageFileName(providerType string, version string, platform string) string {
+	return fmt.Sprintf("terraform-provider-%v_%v_%v.zip", providerType, version, platform)
+}
+
+// zipHash returns the zh: hash of a package, as recorded by terraform init
+func zipHash(content string) string {
+	return fmt.Sprintf("zh:%x", sha256.Sum256([]byte(content)))
+}
+
+// installPackage unpacks a provider package in the root module, as terraform init
+// does, and returns its h1: hash
+func installPackage(g *WithT, dir string, address string, version string, platform string, content string) string {
+	packageDir := filepath.Join(dir, ".terraform", "providers", address, version, platform)
+	g.Expect(os.MkdirAll(packageDir, 0755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(packageDir, "terraform-provider"), []byte(content), 0755)).To(Succeed())
+	hash, err := dirhash.HashDir(packageDir, "", dirhash.Hash1)
+	g.Expect(err).To(BeNil())
+	return hash
+}
+
+func executeLockFile(g *WithT, dir string, params map[string]interface{}, client *http.Client) map[string]policies.PolicyResult {
+	results, err := (&LockFilePolicy{Client: client}).Execute(policies.ProviderPolicyPayload{
+		Policy: policies.PolicyBlock{
+			Type:   "lock_file_policy",
+			Params: params,
+		},
+		WorkingDir: dir,
+	})
+	g.Expect(err).To(BeNil())
+
+	byProvider := make(map[string]policies.PolicyResult)
+	for _, result := range results {
+		byProvider[result.ResourceName] = result
+	}
+	return byProvider
+}
+
+func TestLockFilePolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	registry := newTestRegistry(t, map[string]string{
+		"hashicorp/azurerm/3.44.0/linux_amd64":  "azurerm linux_amd64",
+		"hashicorp/azurerm/3.44.0/darwin_arm64": "azurerm darwin_arm64",
+		"hashicorp/random/3.4.3/linux_amd64":    "random linux_amd64",
+		"hashicorp/random/3.4.3/darwin_arm64":   "random darwin_arm64",
+		"hashicorp/google/4.50.0/linux_amd64":   "google linux_amd64",
+		"hashicorp/google/4.50.0/darwin_arm64":  "google darwin_arm64",
+	})
+
+	dir := t.TempDir()
+	azurermDarwinHash := installPackage(g, dir, azurerm, "3.44.0", "darwin_arm64", "azurerm darwin_arm64")
+	randomDarwinHash := installPackage(g, dir, "registry.terraform.io/hashicorp/random", "3.4.3", "darwin_arm64", "random darwin_arm64")
+	googleDarwinHash := installPackage(g, dir, "registry.terraform.io/hashicorp/google", "4.50.0", "darwin_arm64", "google darwin_arm64")
+
+	lockFile := fmt.Sprintf(testLockFile, azurermDarwinHash, zipHash("azurerm linux_amd64"), randomDarwinHash, googleDarwinHash, zipHash("google linux_amd64"))
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), []byte(testLockFileConfiguration), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(lockFile), 0644)).To(Succeed())
+
+	platforms := []interface{}{"linux_amd64", "darwin_arm64"}
+	results := executeLockFile(g, dir, map[string]interface{}{"platforms": platforms, "registry": true}, registry.Client())
+	g.Expect(results).To(HaveLen(4))
+
+	g.Expect(results[azurerm].Outcome).To(Equal(policies.OUTCOME_SUCCESS))
+
+	random := results["registry.terraform.io/hashicorp/random"]
+	g.Expect(random.Outcome).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(random.Reason).To(Equal("Missing the hash of linux_amd64, run terraform providers lock -platform=linux_amd64 -platform=darwin_arm64"))
+
+	google := results["registry.terraform.io/hashicorp/google"]
+	g.Expect(google.Outcome).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(google.Reason).To(Equal("Locked but not referenced by any configuration"))
+
+	tls := results["registry.terraform.io/hashicorp/tls"]
+	g.Expect(tls.Outcome).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(tls.Reason).To(Equal("Required but missing from the lock file"))
+
+	g.Expect(executeLockFile(g, dir, map[string]interface{}{"platforms": "darwin_arm64"}, nil)["registry.terraform.io/hashicorp/random"].Outcome).To(Equal(policies.OUTCOME_SUCCESS))
+
+	// offline, the platforms not installed are only warned about
+	offline := executeLockFile(g, dir, map[string]interface{}{"platforms": platforms}, nil)[azurerm]
+	g.Expect(offline.Outcome).To(Equal(policies.OUTCOME_WARN))
+	g.Expect(offline.Reason).To(Equal("Cannot verify the hash of linux_amd64: the package of linux_amd64 is not installed and the registry lookup is disabled"))
+
+	// the registry does not publish a package for the platform
+	windows := executeLockFile(g, dir, map[string]interface{}{"platforms": "windows_amd64", "registry": true}, registry.Client())[azurerm]
+	g.Expect(windows.Outcome).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(windows.Reason).To(Equal("Not released for windows_amd64"))
+}
+
+func TestLockFilePolicyRegistryUnreachable(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	lockFile := fmt.Sprintf(testLockFile, "h1:darwin", "zh:linux", "h1:darwin", "h1:darwin", "zh:linux")
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), []byte(testLockFileConfiguration), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(lockFile), 0644)).To(Succeed())
+
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("no route to host")
+	})}
+
+	result := executeLockFile(g, dir, map[string]interface{}{"platforms": "linux_amd64", "registry": true}, client)[azurerm]
+	g.Expect(result.Outcome).To(Equal(policies.OUTCOME_WARN))
+	g.Expect(result.Reason).To(HavePrefix("Cannot verify the hash of linux_amd64: cannot discover the services of registry.terraform.io"))
+}
+
+func TestLockFilePolicyWithoutLockFile(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), []byte(testLockFileConfiguration), 0644)).To(Succeed())
+
+	results := executeLockFile(g, dir, map[string]interface{}{"platforms": "linux_amd64"}, nil)
+	g.Expect(results).To(HaveLen(2))
+	g.Expect(results[azurerm].Reason).To(Equal("Required but missing from the lock file"))
+}
+
+func TestLockFilePolicyCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	check := (&LockFilePolicy{}).Schema().Check
+	g.Expect(check(policies.PolicyBlock{Params: map[string]interface{}{"platforms": []interface{}{"linux_amd64", "windows_386"}}})).To(BeEmpty())
+	g.Expect(check(policies.PolicyBlock{Params: map[string]interface{}{"platforms": "linux-amd64"}})).To(Equal([]string{"invalid platform linux-amd64, expected <os>_<arch>"}))
+}
@@ -0,0 +1,160 @@
+package provider_policies
+
+import (
+	"fmt"
+	"github.com/clearbank/terrapolicy/policies"
+	"github.com/hashicorp/go-version"
+	"log"
+	"strings"
+)
+
+type VersionPolicyStrategy string
+type VersionPolicy struct{}
+
+const (
+	minimum_version VersionPolicyStrategy = "minimum_version"
+	exclude         VersionPolicyStrategy = "exclude"
+	constraint      VersionPolicyStrategy = "constraint"
+	policy_name     string                = "version_policy"
+)
+
+func (s *VersionPolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{
+		Params: map[string]policies.ParamSchema{
+			"provider": {Type: policies.PARAM_STRING, Required: true, Description: "the source address of the provider"},
+			"value":    {Type: policies.PARAM_STRING_LIST, Required: true, Description: "the version, the list of versions, or the version constraints"},
+			"strategy": {Type: policies.PARAM_STRING, Required: true, Values: []string{string(minimum_version), string(exclude), string(constraint)}},
+		},
+		Check: check,
+	}
+}
+
+func check(policy policies.PolicyBlock) []string {
+	values, err := stringValues(policy.Params["value"])
+	if err != nil {
+		return nil // reported by the param type
+	}
+
+	if VersionPolicyStrategy(policy.StringParam("strategy")) == constraint {
+		if _, err := version.NewConstraint(strings.Join(values, ",")); err != nil {
+			return []string{fmt.Sprintf("invalid version constraint: %v", err)}
+		}
+		return nil
+	}
+
+	var problems []string
+	for _, value := range values {
+		if _, err := version.NewVersion(value); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid version %v: %v", value, err))
+		}
+	}
+	return problems
+}
+
+func (s *VersionPolicy) Execute(payload policies.ProviderPolicyPayload) ([]policies.PolicyResult, error) {
+	policy := payload.Policy
+
+	targetProvider, setStrategy := policy.StringParam("provider"), VersionPolicyStrategy(policy.StringParam("strategy"))
+	targetValues, err := stringValues(policy.Params["value"])
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := policies.PolicyResult{ResourceType: "provider", ResourceName: targetProvider}
+
+	installed, found := payload.CurrentProviders[targetProvider]
+	if !found {
+		log.Printf("[DEBUG] provider %v not installed", targetProvider)
+		return []policies.PolicyResult{result}, nil
+	}
+
+	current, err := version.NewVersion(installed.String())
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse version of %v: %v", targetProvider, err)
+	}
+
+	log.Printf("[INFO] %v version: %v", targetProvider, current)
+
+	switch setStrategy {
+	case exclude:
+		for _, value := range targetValues {
+			excluded, err := excludeConstraint(value)
+			if err != nil {
+				return nil, err
+			}
+
+			if excluded.Check(current) {
+				result.Outcome = policies.OUTCOME_FAIL
+				result.Reason = "Excluded version matched"
+			}
+		}
+	case minimum_version:
+		for _, value := range targetValues {
+			minimum, err := version.NewVersion(value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse version: %v", err)
+			}
+
+			if current.LessThan(minimum) {
+				result.Outcome = policies.OUTCOME_FAIL
+				result.Reason = "Minimum provider version not met"
+			}
+		}
+	case constraint:
+		constraints, err := version.NewConstraint(strings.Join(targetValues, ","))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse version constraint: %v", err)
+		}
+
+		if !constraints.Check(current) {
+			result.Outcome = policies.OUTCOME_FAIL
+			result.Reason = fmt.Sprintf("Version %v does not meet constraint %v", current, constraints)
+		}
+	default:
+		result.Outcome = policies.OUTCOME_FAIL
+		result.Reason = "Unknown strategy"
+	}
+
+	return []policies.PolicyResult{result}, nil
+}
+
+// excludeConstraint matches the versions starting with the parts of value:
+// 3 matches 3.x.x, 3.44 matches 3.44.x and 3.44.1 only matches 3.44.1
+func excludeConstraint(value string) (version.Constraints, error) {
+	excluded, err := version.NewVersion(value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse version: %v", err)
+	}
+
+	segments := excluded.Segments()
+	core := strings.SplitN(strings.SplitN(strings.TrimPrefix(value, "v"), "-", 2)[0], "+", 2)[0]
+
+	switch strings.Count(core, ".") {
+	case 0:
+		return version.NewConstraint(fmt.Sprintf("~> %d.0", segments[0]))
+	case 1:
+		return version.NewConstraint(fmt.Sprintf("~> %d.%d.0", segments[0], segments[1]))
+	default:
+		return version.NewConstraint("= " + excluded.String())
+	}
+}
+
+func stringValues(value interface{}) ([]string, error) {
+	switch value := value.(type) {
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		var values []string
+		for _, s := range value {
+			s, ok := s.(string)
+			if !ok {
+				return nil, fmt.Errorf("cannot parse version: %v %T", s, value)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("cannot parse version: %v %T", value, value)
+	}
+}
@@ -0,0 +1,86 @@
+package provider_policies
+
+import (
+	"testing"
+
+	"github.com/clearbank/terrapolicy/internals/providers"
+	"github.com/clearbank/terrapolicy/policies"
+
+	. "github.com/onsi/gomega"
+)
+
+const azurerm = "registry.terraform.io/hashicorp/azurerm"
+
+func execute(g *WithT, installed string, strategy string, value interface{}) policies.PolicyOutcome {
+	results, err := (&VersionPolicy{}).Execute(policies.ProviderPolicyPayload{
+		Policy: policies.PolicyBlock{
+			Type:   "version_policy",
+			Params: map[string]interface{}{"provider": azurerm, "strategy": strategy, "value": value},
+		},
+		CurrentProviders: map[string]policies.ProviderVersion{azurerm: providers.ParseStringVersion(installed)},
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(1))
+	return results[0].Outcome
+}
+
+func TestVersionPolicyConstraint(t *testing.T) {
+	g := NewWithT(t)
+
+	for installed, outcome := range map[string]policies.PolicyOutcome{
+		"3.44.0":      policies.OUTCOME_SUCCESS,
+		"3.44.1":      policies.OUTCOME_FAIL,
+		"3.39.9":      policies.OUTCOME_FAIL,
+		"4.0.0":       policies.OUTCOME_FAIL,
+		"3.45.0-beta": policies.OUTCOME_FAIL,
+	} {
+		g.Expect(execute(g, installed, "constraint", ">= 3.40, < 4.0, != 3.44.1")).To(Equal(outcome), installed)
+	}
+
+	g.Expect(execute(g, "3.51.2", "constraint", "~> 3.50")).To(Equal(policies.OUTCOME_SUCCESS))
+	g.Expect(execute(g, "3.49.0", "constraint", []interface{}{"~> 3.50"})).To(Equal(policies.OUTCOME_FAIL))
+	// pre-releases only meet constraints on a pre-release of the same version
+	g.Expect(execute(g, "3.51.0-beta", "constraint", ">= 3.51.0-alpha")).To(Equal(policies.OUTCOME_SUCCESS))
+	g.Expect(execute(g, "3.51.0-beta", "constraint", "~> 3.50")).To(Equal(policies.OUTCOME_FAIL))
+}
+
+func TestVersionPolicyMinimumVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(execute(g, "3.1.0", "minimum_version", "2.9")).To(Equal(policies.OUTCOME_SUCCESS))
+	g.Expect(execute(g, "3.44.0", "minimum_version", "3.44")).To(Equal(policies.OUTCOME_SUCCESS))
+	g.Expect(execute(g, "3.44.0", "minimum_version", "3.44.1")).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(execute(g, "3.44.0-rc1", "minimum_version", "3.44")).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(execute(g, "3.44.0", "minimum_version", []interface{}{"2.70", "3.66"})).To(Equal(policies.OUTCOME_FAIL))
+}
+
+func TestVersionPolicyExclude(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(execute(g, "3.44.2", "exclude", "3.44")).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(execute(g, "3.44.2", "exclude", "3")).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(execute(g, "3.44.2", "exclude", "3.44.1")).To(Equal(policies.OUTCOME_SUCCESS))
+	g.Expect(execute(g, "3.44.1", "exclude", []interface{}{"2.71", "3.44.1"})).To(Equal(policies.OUTCOME_FAIL))
+}
+
+func TestVersionPolicyCheck(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(check(policies.PolicyBlock{Params: map[string]interface{}{"strategy": "constraint", "value": ">= 3.40, < 4.0"}})).To(BeEmpty())
+	g.Expect(check(policies.PolicyBlock{Params: map[string]interface{}{"strategy": "constraint", "value": ">= three"}})).To(HaveLen(1))
+	g.Expect(check(policies.PolicyBlock{Params: map[string]interface{}{"strategy": "exclude", "value": []interface{}{"3.44", "x"}}})).To(HaveLen(1))
+}
+
+func TestParseStringVersion(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(providers.ParseStringVersion("3")).To(Equal(providers.Version{Major: 3}))
+	g.Expect(providers.ParseStringVersion("3.44")).To(Equal(providers.Version{Major: 3, Minor: 44}))
+	g.Expect(providers.ParseStringVersion("3.44.1-beta.2")).To(Equal(providers.Version{Major: 3, Minor: 44, Patch: 1, Prerelease: "beta.2"}))
+
+	out := "Terraform v1.5.7\non linux_amd64\n+ provider registry.terraform.io/hashicorp/azurerm v3.45.0-rc1"
+	versions, err := providers.ParseTerraformOutput(&out)
+	g.Expect(err).To(BeNil())
+	g.Expect(versions[azurerm].String()).To(Equal("3.45.0-rc1"))
+	g.Expect(versions["terraform"].String()).To(Equal("1.5.7"))
+}
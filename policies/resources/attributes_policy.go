@@ -0,0 +1,348 @@
+package resource_policies
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+
+	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"github.com/clearbank/terrapolicy/policies"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+type AttributesPolicyStrategy string
+type AttributesPolicy struct{}
+
+const (
+	set_if_missing  AttributesPolicyStrategy = "set_if_missing"
+	force_set       AttributesPolicyStrategy = "force_set"
+	fail_if_missing AttributesPolicyStrategy = "fail_if_missing"
+	fail_if_set     AttributesPolicyStrategy = "fail_if_set"
+	policy_name     string                   = "attributes_policy"
+)
+
+func (s *AttributesPolicy) Schema() policies.PolicySchema {
+	params := policies.SelectorParams()
+	params["attribute"] = policies.ParamSchema{Type: policies.PARAM_STRING, Required: true, Description: "the attribute, nested blocks separated by dots"}
+	params["value"] = policies.ParamSchema{Type: policies.PARAM_ANY, Description: "the value set by the set strategies"}
+	params["strategy"] = policies.ParamSchema{
+		Type:     policies.PARAM_STRING,
+		Required: true,
+		Values:   []string{string(fail_if_missing), string(fail_if_set), string(set_if_missing), string(force_set)},
+	}
+
+	return policies.PolicySchema{
+		Params: params,
+		Check: func(block policies.PolicyBlock) []string {
+			if _, err := policies.NewSelector(block.Params); err != nil {
+				return []string{err.Error()}
+			}
+
+			switch AttributesPolicyStrategy(block.StringParam("strategy")) {
+			case set_if_missing, force_set:
+				if block.Params["value"] == nil {
+					return []string{fmt.Sprintf("strategy `%v` requires param `value`", block.StringParam("strategy"))}
+				}
+			}
+			return nil
+		},
+		Conflict: conflict,
+	}
+}
+
+// conflict reports blocks targeting the same attribute whose strategies cannot
+// both be satisfied
+func conflict(a policies.PolicyBlock, b policies.PolicyBlock) (string, bool) {
+	if !policies.SameSelector(a, b) || a.StringParam("attribute") != b.StringParam("attribute") {
+		return "", false
+	}
+
+	strategyA, strategyB := AttributesPolicyStrategy(a.StringParam("strategy")), AttributesPolicyStrategy(b.StringParam("strategy"))
+	if strategyA == fail_if_set && strategyB != fail_if_set || strategyB == fail_if_set && strategyA != fail_if_set {
+		return fmt.Sprintf("`%v` and `%v` on attribute `%v`", strategyA, strategyB, a.StringParam("attribute")), true
+	}
+
+	if strategyA == force_set && strategyB == force_set && !reflect.DeepEqual(a.Params["value"], b.Params["value"]) {
+		return fmt.Sprintf("`%v` of different values on attribute `%v`", force_set, a.StringParam("attribute")), true
+	}
+
+	return "", false
+}
+
+func (s *AttributesPolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	policy, results := payload.Policy, []policies.PolicyResult{}
+
+	targetAttribute, targetValue, setStrategy :=
+		policy.StringParam("attribute"), policy.Params["value"], AttributesPolicyStrategy(policy.StringParam("strategy"))
+
+	selector, err := policies.NewSelector(policy.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	// the schemas and the source index are optional for library callers
+	schemas := payload.Schemas
+	if schemas == nil {
+		schemas = tfschema.NewPluginSource(payload.WorkingDir)
+	}
+	if payload.Source == nil {
+		payload.Source = terraform.NewSourceIndex(payload.FilePath, payload.Hcl)
+	}
+
+	for _, resource := range payload.Hcl.Body().Blocks() {
+		switch t := resource.Type(); t {
+
+		case "resource":
+			if len(resource.Labels()) != 2 {
+				log.Printf("[WARN] skipping resource block with labels %v", resource.Labels())
+				continue
+			}
+
+			currentResource, currentName := terraform.GetResourceType(resource), terraform.GetResourceName(resource)
+			log.Printf("[DEBUG] processing resource \"%v\"", currentResource)
+
+			if !selector.Matches(currentResource, currentName) {
+				log.Printf("[DEBUG] resource \"%v\" not affected by policy", currentResource)
+				continue
+			}
+
+			result := policies.PolicyResult{ResourceType: currentResource, ResourceName: currentName}
+			result.Range, _ = payload.Source.BlockRange(resource)
+
+			attributePath := strings.Split(targetAttribute, ".")
+			if reason, suppressed := isSuppressed(payload, resource, attributePath); suppressed {
+				log.Printf("[INFO] policy %v suppressed on %v: %v", policy.Describe(), result.Address(), reason)
+				result.Outcome = policies.OUTCOME_SUPPRESSED
+				result.Reason = reason
+				results = append(results, result)
+				continue
+			}
+
+			attributeIsSet := isAttributeSet(resource, attributePath)
+			if attributeIsSet && setStrategy == set_if_missing {
+				log.Printf("[DEBUG] attribute already found on resource. skipping due to strategy \"%v\"", setStrategy)
+				results = append(results, result)
+				continue
+			}
+
+			if (attributeIsSet && setStrategy == fail_if_set) || (!attributeIsSet && setStrategy == fail_if_missing) {
+				log.Printf("[DEBUG] failed policy check. attribute set: %v policy: %v", attributeIsSet, setStrategy)
+				result.Outcome = policies.OUTCOME_FAIL
+				result.Reason = "Attribute non conformant"
+				if attribute := findAttribute(resource, attributePath); attribute != nil {
+					if r, ok := payload.Source.AttributeRange(attribute); ok {
+						result.Range = r
+					}
+				}
+				results = append(results, result)
+				continue
+			}
+
+			attributeType := cty.NilType
+			if schema, err := schemas.ResourceSchema(payload.Providers.Resolve(resource), currentResource); err != nil {
+				log.Printf("[WARN] cannot retrieve schema of %v: %v", currentResource, err)
+			} else {
+				attributeType = getTypeForAttribute(schema, attributePath)
+			}
+
+			if attributeType != cty.NilType {
+				v, err := gocty.ToCtyValue(targetValue, attributeType)
+				if err != nil {
+					return results, fmt.Errorf("bad conversion: %v", err)
+				}
+
+				remediations := describeRemediation(payload.Source, resource, attributePath, v)
+				if len(remediations) == 0 {
+					remediations = []policies.Remediation{{}} // not located in the original source
+				}
+				for i := range remediations {
+					remediations[i].Attribute, remediations[i].Value = targetAttribute, targetValue
+				}
+
+				setAttribute(resource.Body(), attributePath, v)
+				log.Printf("[INFO] setting attribute \"%v\" set to %v", targetAttribute, targetValue)
+
+				result.Outcome = policies.OUTCOME_REMEDIATE
+				result.Remediations = append(result.Remediations, remediations...)
+			} else {
+				if payload.Flags.Strict {
+					result.Outcome = policies.OUTCOME_FAIL
+					result.Reason = "Schema failure"
+				} else {
+					log.Printf("[WARN] cannot retrive attribute from schema. continue due to strict mode off: %v", targetAttribute)
+				}
+			}
+			results = append(results, result)
+		default:
+			log.Printf("[DEBUG] skipping block of type: \"%v\"", t)
+		}
+	}
+	return results, nil
+}
+
+func isAttributeSet(block *hclwrite.Block, path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+
+	if len(path) == 1 {
+		tagsAttribute := block.Body().GetAttribute(path[0])
+
+		if tagsAttribute != nil {
+			tokens := tagsAttribute.Expr().BuildTokens(hclwrite.Tokens{})
+			return tokens != nil
+		}
+
+		return false
+	}
+
+	var blocks []*hclwrite.Block
+	for _, block := range block.Body().Blocks() {
+		log.Printf("[DEBUG] checking block %v against path %v", block.Type(), path)
+		if block.Type() == path[0] {
+			blocks = append(blocks, block)
+		}
+	}
+
+	var result bool = len(blocks) > 0
+	for _, block := range blocks {
+		result = result && isAttributeSet(block, path[1:])
+	}
+
+	return result
+}
+
+func isSuppressed(payload policies.ResourcePolicyPayload, resource *hclwrite.Block, path []string) (string, bool) {
+	if reason, suppressed := payload.Suppression(resource.BuildTokens(nil)); suppressed {
+		return reason, true
+	}
+
+	if attribute := findAttribute(resource, path); attribute != nil {
+		return payload.Suppression(attribute.BuildTokens(nil))
+	}
+
+	return "", false
+}
+
+func findAttribute(block *hclwrite.Block, path []string) *hclwrite.Attribute {
+	if len(path) == 0 {
+		return nil
+	}
+
+	if len(path) == 1 {
+		return block.Body().GetAttribute(path[0])
+	}
+
+	for _, nestedBlock := range block.Body().Blocks() {
+		if nestedBlock.Type() == path[0] {
+			if attribute := findAttribute(nestedBlock, path[1:]); attribute != nil {
+				return attribute
+			}
+		}
+	}
+
+	return nil
+}
+
+// describeRemediation locates where setAttribute writes the value in the original
+// source: over the existing attribute, or before the closing brace of the
+// deepest existing block on the path, for each of the blocks it rewrites. Must
+// run before the file is modified
+func describeRemediation(source *terraform.SourceIndex, block *hclwrite.Block, path []string, value cty.Value) []policies.Remediation {
+	if len(path) == 1 {
+		if attribute := block.Body().GetAttribute(path[0]); attribute != nil {
+			if r, ok := source.AttributeRange(attribute); ok {
+				text := path[0] + " = " + string(hclwrite.TokensForValue(value).Bytes())
+				return []policies.Remediation{{Range: r, Text: text}}
+			}
+			return nil
+		}
+	} else {
+		var remediations []policies.Remediation
+		nested := false
+		for _, nestedBlock := range block.Body().Blocks() {
+			if nestedBlock.Type() == path[0] {
+				nested = true
+				remediations = append(remediations, describeRemediation(source, nestedBlock, path[1:], value)...)
+			}
+		}
+		if nested {
+			return remediations
+		}
+	}
+
+	closingBrace, ok := source.ClosingBrace(block)
+	if !ok {
+		return nil
+	}
+
+	f := hclwrite.NewEmptyFile()
+	setAttribute(f.Body(), path, value)
+
+	indent := strings.Repeat(" ", closingBrace.Start.Column+1)
+	var text strings.Builder
+	for _, line := range strings.SplitAfter(string(hclwrite.Format(f.Bytes())), "\n") {
+		if strings.TrimSpace(line) != "" {
+			text.WriteString(indent + line)
+		}
+	}
+
+	insertAt := closingBrace.Start
+	insertAt.Byte -= insertAt.Column - 1
+	insertAt.Column = 1
+
+	return []policies.Remediation{{
+		Range: hcl.Range{Filename: closingBrace.Filename, Start: insertAt, End: insertAt},
+		Text:  text.String(),
+	}}
+}
+
+func getTypeForAttribute(schema *tfschema.Block, path []string) cty.Type {
+	if len(path) == 1 {
+		attributeSchema := schema.Attributes[path[0]]
+		if attributeSchema != nil {
+			return attributeSchema.Type.Type
+		}
+		return cty.NilType
+	}
+
+	if nestedBlock, found := schema.BlockTypes[path[0]]; found {
+		return getTypeForAttribute(&nestedBlock.Block, path[1:])
+	}
+
+	return cty.NilType
+}
+
+func setAttribute(body *hclwrite.Body, path []string, value cty.Value) {
+	if len(path) == 0 {
+		return
+	}
+
+	if len(path) == 1 {
+		body.SetAttributeValue(path[0], value)
+		return
+	}
+
+	var blocks []*hclwrite.Block
+	for _, block := range body.Blocks() {
+		if block.Type() == path[0] {
+			blocks = append(blocks, block)
+		}
+	}
+
+	if len(blocks) > 0 {
+		for _, block := range blocks {
+			setAttribute(block.Body(), path[1:], value)
+		}
+		return
+	}
+
+	block := body.AppendNewBlock(path[0], nil)
+	setAttribute(block.Body(), path[1:], value)
+}
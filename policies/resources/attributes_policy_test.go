@@ -0,0 +1,124 @@
+package resource_policies
+
+import (
+	"testing"
+
+	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/policies"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/minamijoyo/tfschema/tfschema"
+	"github.com/zclconf/go-cty/cty"
+
+	. "github.com/onsi/gomega"
+)
+
+// staticSchemas serves the same schema for every resource type
+type staticSchemas struct {
+	block *tfschema.Block
+}
+
+func (s staticSchemas) ResourceSchema(provider policies.ProviderRef, resourceType string) (*tfschema.Block, error) {
+	return s.block, nil
+}
+
+var networkRulesSchema = &tfschema.Block{
+	BlockTypes: map[string]*tfschema.NestedBlock{
+		"network_rules": {
+			Block: tfschema.Block{
+				Attributes: map[string]*tfschema.Attribute{
+					"default_action": {Type: tfschema.Type{Type: cty.String}, Optional: true},
+				},
+			},
+		},
+	},
+}
+
+const networkRulesSource = `resource "azurerm_storage_account" "storage" {
+  network_rules {
+    default_action = "Allow"
+  }
+
+  network_rules {
+  }
+}
+`
+
+func executeAttributes(g *WithT, src string, params map[string]interface{}, schemas policies.SchemaSource) ([]policies.PolicyResult, *hclwrite.File) {
+	f, diags := hclwrite.ParseConfig([]byte(src), "main.tf", hcl.InitialPos)
+	g.Expect(diags.HasErrors()).To(BeFalse())
+
+	results, err := (&AttributesPolicy{}).Execute(policies.ResourcePolicyPayload{
+		Hcl:     f,
+		Source:  terraform.NewSourceIndex("main.tf", f),
+		Policy:  policies.PolicyBlock{Type: "attributes_policy", Params: params},
+		Schemas: schemas,
+	})
+	g.Expect(err).To(BeNil())
+	return results, f
+}
+
+func TestAttributesPolicyNestedRemediations(t *testing.T) {
+	g := NewWithT(t)
+
+	results, _ := executeAttributes(g, networkRulesSource, map[string]interface{}{
+		"resource":  "azurerm_storage_account",
+		"attribute": "network_rules.default_action",
+		"value":     "Deny",
+		"strategy":  "force_set",
+	}, staticSchemas{block: networkRulesSchema})
+
+	g.Expect(results).To(HaveLen(1))
+	g.Expect(results[0].Outcome).To(Equal(policies.OUTCOME_REMEDIATE))
+
+	// a remediation per rewritten block
+	remediations := results[0].Remediations
+	g.Expect(remediations).To(HaveLen(2))
+	g.Expect(remediations[0].Range.Start.Line).To(Equal(3))
+	g.Expect(remediations[0].Text).To(Equal(`default_action = "Deny"`))
+	g.Expect(remediations[1].Range.Start.Line).To(Equal(7))
+	g.Expect(remediations[1].Text).To(Equal("    default_action = \"Deny\"\n"))
+	g.Expect(remediations[1].Attribute).To(Equal("network_rules.default_action"))
+}
+
+func TestAttributesPolicyWithoutSchemas(t *testing.T) {
+	g := NewWithT(t)
+
+	for strict, outcome := range map[bool]policies.PolicyOutcome{false: policies.OUTCOME_SUCCESS, true: policies.OUTCOME_FAIL} {
+		f, diags := hclwrite.ParseConfig([]byte(networkRulesSource), "main.tf", hcl.InitialPos)
+		g.Expect(diags.HasErrors()).To(BeFalse())
+
+		// falls back to the provider plugins of the working directory, which has none
+		results, err := (&AttributesPolicy{}).Execute(policies.ResourcePolicyPayload{
+			Hcl:        f,
+			Source:     terraform.NewSourceIndex("main.tf", f),
+			Policy:     policies.PolicyBlock{Type: "attributes_policy", Params: map[string]interface{}{"resource": "azurerm_storage_account", "attribute": "min_tls_version", "value": "TLS1_2", "strategy": "force_set"}},
+			WorkingDir: t.TempDir(),
+			Flags:      policies.PolicyExecutionFlags{Strict: strict},
+		})
+		g.Expect(err).To(BeNil())
+		g.Expect(results).To(HaveLen(1))
+		g.Expect(results[0].Outcome).To(Equal(outcome))
+	}
+}
+
+func TestAttributesPolicyWithoutSource(t *testing.T) {
+	g := NewWithT(t)
+
+	f, diags := hclwrite.ParseConfig([]byte(networkRulesSource), "main.tf", hcl.InitialPos)
+	g.Expect(diags.HasErrors()).To(BeFalse())
+
+	// the source index is built from the file
+	results, err := (&AttributesPolicy{}).Execute(policies.ResourcePolicyPayload{
+		Hcl:      f,
+		Policy:   policies.PolicyBlock{Type: "attributes_policy", Params: map[string]interface{}{"resource": "azurerm_storage_account", "attribute": "network_rules.default_action", "value": "Deny", "strategy": "force_set"}},
+		FilePath: "main.tf",
+		Schemas:  staticSchemas{block: networkRulesSchema},
+	})
+	g.Expect(err).To(BeNil())
+	g.Expect(results).To(HaveLen(1))
+	g.Expect(results[0].Outcome).To(Equal(policies.OUTCOME_REMEDIATE))
+	g.Expect(results[0].Range.Start.Line).To(BeNumerically(">", 0))
+	g.Expect(results[0].Remediations).To(HaveLen(2))
+}
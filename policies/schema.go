@@ -0,0 +1,49 @@
+package policies
+
+type ParamType string
+
+const (
+	PARAM_STRING      ParamType = "string"
+	PARAM_STRING_LIST ParamType = "string_list" // a string or a list of strings
+	PARAM_BOOL        ParamType = "bool"
+	PARAM_ANY         ParamType = "any"
+)
+
+// ParamSchema declares a parameter of a policy type. Values, when set, lists
+// the values accepted by a string parameter
+type ParamSchema struct {
+	Type        ParamType
+	Required    bool
+	Values      []string
+	Description string
+}
+
+// PolicySchema declares the parameters of a policy type and, optionally, the
+// rules its blocks must follow. Check reports problems of the combination of
+// parameters of a block, Conflict reports why two blocks of the type contradict
+// each other. Both are only called for blocks with valid parameters
+type PolicySchema struct {
+	Params   map[string]ParamSchema
+	Check    func(block PolicyBlock) []string
+	Conflict func(a PolicyBlock, b PolicyBlock) (string, bool)
+}
+
+// Schemas maps the policy types of each section of the policy file to their schema
+type Schemas struct {
+	Providers map[string]PolicySchema
+	Resources map[string]PolicySchema
+}
+
+// StringParam returns a string parameter of the block, or an empty string when
+// it is missing or is not a string
+func (b PolicyBlock) StringParam(name string) string {
+	s, _ := b.Params[name].(string)
+	return s
+}
+
+// BoolParam returns a boolean parameter of the block, or false when it is missing
+// or is not a boolean
+func (b PolicyBlock) BoolParam(name string) bool {
+	v, _ := b.Params[name].(bool)
+	return v
+}
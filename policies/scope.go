@@ -0,0 +1,84 @@
+package policies
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/bmatcuk/doublestar"
+)
+
+const (
+	SCOPE_MODULES_ALL      = "all"
+	SCOPE_MODULES_ROOT     = "root"
+	SCOPE_MODULES_CHILDREN = "children"
+)
+
+// Scope restricts a resource policy to some of the terraform files. Include and
+// Exclude are globs over the file paths relative to the root module. Modules
+// selects the root module, its child modules or both, and the child modules can
+// be filtered by key and source with ModuleKeys and ModuleSources
+type Scope struct {
+	Include       []string `yaml:"include,omitempty"`
+	Exclude       []string `yaml:"exclude,omitempty"`
+	Modules       string   `yaml:"modules,omitempty"`
+	ModuleKeys    []string `yaml:"module_keys,omitempty"`
+	ModuleSources []string `yaml:"module_sources,omitempty"`
+}
+
+// Matches reports whether a file is in scope. relPath is the path of the file
+// relative to the root module
+func (s Scope) Matches(relPath string, module ModuleMetadata) bool {
+	isRoot := module.Key == ""
+
+	switch s.Modules {
+	case SCOPE_MODULES_ROOT:
+		if !isRoot {
+			return false
+		}
+	case SCOPE_MODULES_CHILDREN:
+		if isRoot {
+			return false
+		}
+	}
+
+	if !isRoot {
+		if len(s.ModuleKeys) > 0 && !matchAny(s.ModuleKeys, module.Key) {
+			return false
+		}
+		if len(s.ModuleSources) > 0 && !matchAnyPath(s.ModuleSources, module.Source) {
+			return false
+		}
+	}
+
+	if len(s.Include) > 0 && !matchAnyPath(s.Include, relPath) {
+		return false
+	}
+
+	return !matchAnyPath(s.Exclude, relPath)
+}
+
+func (s Scope) Validate() error {
+	switch s.Modules {
+	case "", SCOPE_MODULES_ALL, SCOPE_MODULES_ROOT, SCOPE_MODULES_CHILDREN:
+	default:
+		return fmt.Errorf("unknown modules `%v`, expected one of: %v,%v,%v", s.Modules, SCOPE_MODULES_ALL, SCOPE_MODULES_ROOT, SCOPE_MODULES_CHILDREN)
+	}
+
+	// path.Match validates the whole pattern, doublestar stops at the first mismatch
+	for _, pattern := range append(append(append(append([]string{}, s.Include...), s.Exclude...), s.ModuleKeys...), s.ModuleSources...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern `%v`: %v", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func matchAnyPath(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := doublestar.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package policies
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestScope(t *testing.T) {
+	g := NewWithT(t)
+
+	root := ModuleMetadata{Dir: "."}
+	local := ModuleMetadata{Key: "network", Source: "./modules/network", Dir: "modules/network"}
+	registry := ModuleMetadata{Key: "storage", Source: "registry.terraform.io/Azure/storage/azurerm", Dir: ".terraform/modules/storage"}
+
+	g.Expect(Scope{}.Matches("main.tf", root)).To(BeTrue())
+	g.Expect(Scope{}.Matches(".terraform/modules/storage/main.tf", registry)).To(BeTrue())
+
+	g.Expect(Scope{Modules: SCOPE_MODULES_ROOT}.Matches("main.tf", root)).To(BeTrue())
+	g.Expect(Scope{Modules: SCOPE_MODULES_ROOT}.Matches("modules/network/main.tf", local)).To(BeFalse())
+	g.Expect(Scope{Modules: SCOPE_MODULES_CHILDREN}.Matches("main.tf", root)).To(BeFalse())
+
+	ownCode := Scope{ModuleSources: []string{"./**"}}
+	g.Expect(ownCode.Matches("main.tf", root)).To(BeTrue())
+	g.Expect(ownCode.Matches("modules/network/main.tf", local)).To(BeTrue())
+	g.Expect(ownCode.Matches(".terraform/modules/storage/main.tf", registry)).To(BeFalse())
+
+	g.Expect(Scope{ModuleKeys: []string{"net*"}}.Matches(".terraform/modules/storage/main.tf", registry)).To(BeFalse())
+	g.Expect(Scope{ModuleKeys: []string{"net*"}}.Matches("modules/network/main.tf", local)).To(BeTrue())
+
+	g.Expect(Scope{Include: []string{"modules/**"}}.Matches("main.tf", root)).To(BeFalse())
+	g.Expect(Scope{Include: []string{"modules/**"}}.Matches("modules/network/main.tf", local)).To(BeTrue())
+	g.Expect(Scope{Exclude: []string{"**/legacy_*.tf"}}.Matches("modules/network/legacy_vnet.tf", local)).To(BeFalse())
+}
+
+func TestScopeValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(Scope{Modules: SCOPE_MODULES_ROOT, Include: []string{"**/*.tf"}}.Validate()).To(Succeed())
+	g.Expect(Scope{Modules: "vendored"}.Validate()).To(MatchError("unknown modules `vendored`, expected one of: all,root,children"))
+	g.Expect(Scope{Exclude: []string{"[main.tf"}}.Validate()).To(HaveOccurred())
+}
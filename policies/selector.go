@@ -0,0 +1,124 @@
+package policies
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+)
+
+const (
+	SELECTOR_RESOURCE       = "resource"
+	SELECTOR_RESOURCE_REGEX = "resource_regex"
+	SELECTOR_RESOURCE_NAME  = "resource_name"
+	SELECTOR_EXCLUDE        = "exclude"
+)
+
+// Selector selects the resources a resource policy applies to, by type and name.
+// A resource is selected if its type matches any of Types or Regex, its name
+// matches any of Names when set, and neither its type nor its address match
+// any of Exclude
+type Selector struct {
+	Types   []string
+	Regex   *regexp.Regexp
+	Names   []string
+	Exclude []string
+}
+
+// SelectorParams returns the schema of the selector params, to be added to the
+// params of resource policy types
+func SelectorParams() map[string]ParamSchema {
+	return map[string]ParamSchema{
+		SELECTOR_RESOURCE:       {Type: PARAM_STRING_LIST, Description: "globs over resource types, e.g. azurerm_storage_*"},
+		SELECTOR_RESOURCE_REGEX: {Type: PARAM_STRING, Description: "regular expression matching the whole resource type"},
+		SELECTOR_RESOURCE_NAME:  {Type: PARAM_STRING_LIST, Description: "globs over resource names"},
+		SELECTOR_EXCLUDE:        {Type: PARAM_STRING_LIST, Description: "globs over resource types or addresses excluded from the policy"},
+	}
+}
+
+// NewSelector builds the selector from the params of a policy block
+func NewSelector(params map[string]interface{}) (Selector, error) {
+	selector := Selector{}
+	var err error
+
+	if selector.Types, err = stringList(params, SELECTOR_RESOURCE); err != nil {
+		return selector, err
+	}
+	if selector.Names, err = stringList(params, SELECTOR_RESOURCE_NAME); err != nil {
+		return selector, err
+	}
+	if selector.Exclude, err = stringList(params, SELECTOR_EXCLUDE); err != nil {
+		return selector, err
+	}
+
+	if expr, ok := params[SELECTOR_RESOURCE_REGEX].(string); ok {
+		if selector.Regex, err = regexp.Compile("^(?:" + expr + ")$"); err != nil {
+			return selector, fmt.Errorf("invalid %v: %v", SELECTOR_RESOURCE_REGEX, err)
+		}
+	}
+
+	if len(selector.Types) == 0 && selector.Regex == nil {
+		return selector, fmt.Errorf("param `%v` or `%v` is required", SELECTOR_RESOURCE, SELECTOR_RESOURCE_REGEX)
+	}
+
+	for _, pattern := range append(append(append([]string{}, selector.Types...), selector.Names...), selector.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return selector, fmt.Errorf("invalid pattern `%v`: %v", pattern, err)
+		}
+	}
+
+	return selector, nil
+}
+
+func (s Selector) Matches(resourceType string, resourceName string) bool {
+	if !matchAny(s.Types, resourceType) && (s.Regex == nil || !s.Regex.MatchString(resourceType)) {
+		return false
+	}
+
+	if len(s.Names) > 0 && !matchAny(s.Names, resourceName) {
+		return false
+	}
+
+	return !matchAny(s.Exclude, resourceType) && !matchAny(s.Exclude, resourceType+"."+resourceName)
+}
+
+// SameSelector reports whether two policy blocks declare the same selector
+func SameSelector(a PolicyBlock, b PolicyBlock) bool {
+	for name := range SelectorParams() {
+		if !reflect.DeepEqual(a.Params[name], b.Params[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList reads a param declared as a string or a list of strings
+func stringList(params map[string]interface{}, name string) ([]string, error) {
+	switch value := params[name].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{value}, nil
+	case []interface{}:
+		list := make([]string, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("param `%v` must be a string or a list of strings", name)
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("param `%v` must be a string or a list of strings", name)
+	}
+}
@@ -0,0 +1,46 @@
+package policies
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSelector(t *testing.T) {
+	g := NewWithT(t)
+
+	selector, err := NewSelector(map[string]interface{}{
+		"resource": "azurerm_*",
+		"exclude":  []interface{}{"azurerm_resource_group", "azurerm_storage_account.legacy"},
+	})
+	g.Expect(err).To(BeNil())
+
+	g.Expect(selector.Matches("azurerm_storage_account", "logs")).To(BeTrue())
+	g.Expect(selector.Matches("azurerm_storage_account", "legacy")).To(BeFalse())
+	g.Expect(selector.Matches("azurerm_resource_group", "main")).To(BeFalse())
+	g.Expect(selector.Matches("aws_s3_bucket", "logs")).To(BeFalse())
+
+	selector, err = NewSelector(map[string]interface{}{
+		"resource_regex": "azurerm_(storage|application)_.*",
+		"resource_name":  []interface{}{"prod_*"},
+	})
+	g.Expect(err).To(BeNil())
+
+	g.Expect(selector.Matches("azurerm_storage_account", "prod_logs")).To(BeTrue())
+	g.Expect(selector.Matches("azurerm_application_insights", "prod")).To(BeFalse())
+	g.Expect(selector.Matches("azurerm_storage_account", "dev_logs")).To(BeFalse())
+	g.Expect(selector.Matches("my_azurerm_storage_account", "prod_logs")).To(BeFalse())
+}
+
+func TestSelectorErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := NewSelector(map[string]interface{}{"resource_name": "test"})
+	g.Expect(err).To(MatchError("param `resource` or `resource_regex` is required"))
+
+	_, err = NewSelector(map[string]interface{}{"resource_regex": "azurerm_("})
+	g.Expect(err).To(HaveOccurred())
+
+	_, err = NewSelector(map[string]interface{}{"resource": "azurerm_[storage"})
+	g.Expect(err).To(HaveOccurred())
+}
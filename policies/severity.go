@@ -0,0 +1,98 @@
+package policies
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Severity uint64
+
+// the zero value marks a policy block without severity, see PolicyBlock.GetSeverity
+const (
+	SEVERITY_INFO Severity = iota + 1
+	SEVERITY_LOW
+	SEVERITY_MEDIUM
+	SEVERITY_HIGH
+	SEVERITY_CRITICAL
+)
+
+const DEFAULT_SEVERITY = SEVERITY_HIGH
+
+var severityNames = map[Severity]string{
+	SEVERITY_INFO:     "info",
+	SEVERITY_LOW:      "low",
+	SEVERITY_MEDIUM:   "medium",
+	SEVERITY_HIGH:     "high",
+	SEVERITY_CRITICAL: "critical",
+}
+
+func ParseSeverity(s string) (Severity, error) {
+	for severity, name := range severityNames {
+		if strings.EqualFold(name, s) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity: %v", s)
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return ""
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = 0
+		return nil
+	}
+
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+
+	*s = severity
+	return nil
+}
+
+func (s *Severity) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err != nil {
+		return err
+	}
+
+	severity, err := ParseSeverity(name)
+	if err != nil {
+		return err
+	}
+
+	*s = severity
+	return nil
+}
+
+// OrLowest returns the severity, or SEVERITY_INFO when it is not set: a fail-on
+// threshold that is not set fails on every severity
+func (s Severity) OrLowest() Severity {
+	if s == 0 {
+		return SEVERITY_INFO
+	}
+	return s
+}
+
+// IsBlocking reports whether a failure of this severity fails the execution. Everything is blocking when failOn is not set
+func (s Severity) IsBlocking(failOn Severity) bool {
+	return s >= failOn
+}
+
+func (b PolicyBlock) GetSeverity() Severity {
+	if b.Severity == 0 {
+		return DEFAULT_SEVERITY
+	}
+	return b.Severity
+}
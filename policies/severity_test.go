@@ -0,0 +1,16 @@
+package policies
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSeverityOrLowest(t *testing.T) {
+	g := NewWithT(t)
+
+	var unset Severity
+	g.Expect(unset.OrLowest()).To(Equal(SEVERITY_INFO))
+	g.Expect(SEVERITY_HIGH.OrLowest()).To(Equal(SEVERITY_HIGH))
+	g.Expect(SEVERITY_INFO.IsBlocking(unset.OrLowest())).To(BeTrue())
+}
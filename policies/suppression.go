@@ -0,0 +1,67 @@
+package policies
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// matches `# terrapolicy:ignore=<policy-id>[,<policy-id>] reason="..."`
+var suppression_pattern = regexp.MustCompile(`terrapolicy:ignore=([^\s]+)(?:\s+reason="([^"]*)")?`)
+
+// Suppression looks for a terrapolicy:ignore comment matching the policy id above or
+// on the first line of the given block or attribute tokens, and returns its reason
+func (p ResourcePolicyPayload) Suppression(tokens hclwrite.Tokens) (string, bool) {
+	if p.Policy.Id == "" {
+		return "", false
+	}
+
+	for _, comment := range comments(tokens) {
+		match := suppression_pattern.FindStringSubmatch(comment)
+		if match == nil {
+			continue
+		}
+
+		for _, id := range strings.Split(match[1], ",") {
+			if id != p.Policy.Id {
+				continue
+			}
+
+			if p.Flags.DisallowSuppressions {
+				log.Printf("[WARN] ignoring suppression of policy `%v` in %v as suppressions are disallowed", id, p.FilePath)
+				return "", false
+			}
+
+			return match[2], true
+		}
+	}
+
+	return "", false
+}
+
+// comments returns the leading comments and the comments on the same line as the first token
+func comments(tokens hclwrite.Tokens) []string {
+	var comments []string
+	started := false
+
+	for _, token := range tokens {
+		switch {
+		case token.Type == hclsyntax.TokenComment:
+			comments = append(comments, string(token.Bytes))
+			if started && strings.HasSuffix(string(token.Bytes), "\n") {
+				return comments
+			}
+		case token.Type == hclsyntax.TokenNewline:
+			if started {
+				return comments
+			}
+		default:
+			started = true
+		}
+	}
+
+	return comments
+}
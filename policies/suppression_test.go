@@ -0,0 +1,64 @@
+package policies
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+
+	. "github.com/onsi/gomega"
+)
+
+const suppressionSource = `
+# terrapolicy:ignore=above-block reason="approved exception"
+resource "azurerm_storage_account" "above" {
+  name = "above"
+}
+
+resource "azurerm_storage_account" "header" { # terrapolicy:ignore=other,on-header
+  name = "header"
+}
+
+resource "azurerm_storage_account" "attribute" {
+  # terrapolicy:ignore=above-attribute reason="legacy"
+  name            = "attribute"
+  min_tls_version = "TLS1_0" # terrapolicy:ignore=on-attribute reason="migrating"
+}
+`
+
+func TestSuppression(t *testing.T) {
+	g := NewWithT(t)
+
+	f, diags := hclwrite.ParseConfig([]byte(suppressionSource), "main.tf", hcl.InitialPos)
+	g.Expect(diags.HasErrors()).To(BeFalse())
+	blocks := f.Body().Blocks()
+
+	suppression := func(id string, tokens hclwrite.Tokens, flags PolicyExecutionFlags) (string, bool) {
+		payload := ResourcePolicyPayload{Policy: PolicyBlock{Id: id}, Flags: flags}
+		return payload.Suppression(tokens)
+	}
+
+	reason, ok := suppression("above-block", blocks[0].BuildTokens(nil), PolicyExecutionFlags{})
+	g.Expect(ok).To(BeTrue())
+	g.Expect(reason).To(Equal("approved exception"))
+
+	_, ok = suppression("on-header", blocks[1].BuildTokens(nil), PolicyExecutionFlags{})
+	g.Expect(ok).To(BeTrue())
+
+	_, ok = suppression("above-attribute", blocks[2].BuildTokens(nil), PolicyExecutionFlags{})
+	g.Expect(ok).To(BeFalse())
+
+	reason, ok = suppression("above-attribute", blocks[2].Body().GetAttribute("name").BuildTokens(nil), PolicyExecutionFlags{})
+	g.Expect(ok).To(BeTrue())
+	g.Expect(reason).To(Equal("legacy"))
+
+	reason, ok = suppression("on-attribute", blocks[2].Body().GetAttribute("min_tls_version").BuildTokens(nil), PolicyExecutionFlags{})
+	g.Expect(ok).To(BeTrue())
+	g.Expect(reason).To(Equal("migrating"))
+
+	_, ok = suppression("above-block", blocks[0].BuildTokens(nil), PolicyExecutionFlags{DisallowSuppressions: true})
+	g.Expect(ok).To(BeFalse())
+
+	_, ok = suppression("", blocks[0].BuildTokens(nil), PolicyExecutionFlags{})
+	g.Expect(ok).To(BeFalse())
+}
@@ -0,0 +1,135 @@
+package policies
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"time"
+
+	"github.com/bmatcuk/doublestar"
+	"github.com/clearbank/terrapolicy/internals/file"
+	"gopkg.in/yaml.v3"
+)
+
+const date_layout = "2006-01-02"
+
+// Waiver is a centrally managed exception to a policy, identified by its id,
+// for the resources matching Resource and the files matching File
+type Waiver struct {
+	Policy        string `yaml:"policy"`
+	Resource      string `yaml:"resource,omitempty"`
+	File          string `yaml:"file,omitempty"`
+	Justification string `yaml:"justification"`
+	Approver      string `yaml:"approver"`
+	Expires       Date   `yaml:"expires"`
+}
+
+type Date struct {
+	time.Time
+}
+
+type waiversFile struct {
+	Waivers []Waiver `yaml:"waivers"`
+}
+
+func ParseWaivers(path string) ([]Waiver, error) {
+	waivers := waiversFile{}
+	data, err := file.ReadFile(path)
+
+	if err != nil {
+		log.Printf("[ERROR] %v", err)
+		return nil, errors.New("waivers_read")
+	}
+
+	if err := yaml.Unmarshal(data, &waivers); err != nil {
+		log.Printf("[ERROR] %v", err)
+		return nil, errors.New("unmarshal_error")
+	}
+
+	for i, waiver := range waivers.Waivers {
+		if err := validateWaiver(waiver); err != nil {
+			log.Printf("[ERROR] waivers[%d]: %v", i, err)
+			return nil, errors.New("invalid_waiver")
+		}
+	}
+
+	return waivers.Waivers, nil
+}
+
+// Matches reports whether the waiver covers the finding. relPath is the path of
+// the file of the finding relative to the root module
+func (w Waiver) Matches(finding Finding, relPath string) bool {
+	if w.Policy != finding.Policy.Id {
+		return false
+	}
+
+	if w.Resource != "" {
+		if ok, _ := path.Match(w.Resource, finding.Result.Address()); !ok {
+			return false
+		}
+	}
+
+	if w.File != "" {
+		if ok, _ := doublestar.Match(w.File, relPath); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsExpired reports whether the waiver expired. Waivers are valid until the end of their expiry day
+func (w Waiver) IsExpired(now time.Time) bool {
+	return !now.Before(w.Expires.AddDate(0, 0, 1))
+}
+
+func (w Waiver) Describe() string {
+	return fmt.Sprintf("waived by %v until %v: %v", w.Approver, w.Expires, w.Justification)
+}
+
+func (d Date) String() string {
+	return d.Format(date_layout)
+}
+
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Date) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	t, err := time.Parse(date_layout, s)
+	if err != nil {
+		return fmt.Errorf("invalid date %v, expected format YYYY-MM-DD", s)
+	}
+
+	d.Time = t
+	return nil
+}
+
+func validateWaiver(waiver Waiver) error {
+	switch {
+	case waiver.Policy == "":
+		return errors.New("policy is required")
+	case waiver.Resource == "" && waiver.File == "":
+		return errors.New("resource or file is required")
+	case waiver.Justification == "":
+		return errors.New("justification is required")
+	case waiver.Approver == "":
+		return errors.New("approver is required")
+	case waiver.Expires.IsZero():
+		return errors.New("expires is required")
+	}
+
+	if _, err := path.Match(waiver.Resource, ""); err != nil {
+		return fmt.Errorf("invalid resource pattern: %v", err)
+	}
+	if _, err := path.Match(waiver.File, ""); err != nil {
+		return fmt.Errorf("invalid file pattern: %v", err)
+	}
+	return nil
+}
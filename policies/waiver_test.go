@@ -0,0 +1,63 @@
+package policies
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseWaivers(t *testing.T) {
+	g := NewWithT(t)
+
+	path := writePolicy(t, `
+waivers:
+  - policy: storage-tls
+    resource: azurerm_storage_account.legacy_*
+    file: "modules/**/*.tf"
+    justification: decommissioned in Q4
+    approver: security@example.com
+    expires: 2030-01-31
+`)
+
+	waivers, err := ParseWaivers(path)
+	g.Expect(err).To(BeNil())
+	g.Expect(waivers).To(HaveLen(1))
+	g.Expect(waivers[0].Policy).To(Equal("storage-tls"))
+	g.Expect(waivers[0].Expires.String()).To(Equal("2030-01-31"))
+
+	path = writePolicy(t, `
+waivers:
+  - policy: storage-tls
+    resource: azurerm_storage_account.legacy
+    justification: decommissioned in Q4
+    expires: 2030-01-31
+`)
+
+	_, err = ParseWaivers(path)
+	g.Expect(err).To(MatchError("invalid_waiver"))
+}
+
+func TestWaiverMatches(t *testing.T) {
+	g := NewWithT(t)
+
+	finding := Finding{
+		Policy:   PolicyBlock{Id: "storage-tls"},
+		FilePath: "/root/modules/storage/main.tf",
+		Result:   PolicyResult{Outcome: OUTCOME_FAIL, ResourceType: "azurerm_storage_account", ResourceName: "legacy_logs"},
+	}
+
+	g.Expect(Waiver{Policy: "storage-tls", Resource: "azurerm_storage_account.legacy_*"}.Matches(finding, "modules/storage/main.tf")).To(BeTrue())
+	g.Expect(Waiver{Policy: "storage-tls", File: "modules/**/*.tf"}.Matches(finding, "modules/storage/main.tf")).To(BeTrue())
+	g.Expect(Waiver{Policy: "storage-tls", Resource: "azurerm_storage_account.*", File: "main.tf"}.Matches(finding, "modules/storage/main.tf")).To(BeFalse())
+	g.Expect(Waiver{Policy: "other", Resource: "azurerm_storage_account.legacy_logs"}.Matches(finding, "modules/storage/main.tf")).To(BeFalse())
+}
+
+func TestWaiverIsExpired(t *testing.T) {
+	g := NewWithT(t)
+
+	waiver := Waiver{Expires: Date{time.Date(2030, 1, 31, 0, 0, 0, 0, time.UTC)}}
+
+	g.Expect(waiver.IsExpired(time.Date(2030, 1, 31, 23, 59, 0, 0, time.UTC))).To(BeFalse())
+	g.Expect(waiver.IsExpired(time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC))).To(BeTrue())
+}
@@ -0,0 +1,68 @@
+package terrapolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clearbank/terrapolicy/policies"
+
+	. "github.com/onsi/gomega"
+)
+
+type ownerTagPolicy struct{}
+
+func (p *ownerTagPolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{Params: policies.SelectorParams()}
+}
+
+func (p *ownerTagPolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	var results []policies.PolicyResult
+	for _, block := range payload.Hcl.Body().Blocks() {
+		if block.Type() != "resource" {
+			continue
+		}
+
+		result := policies.PolicyResult{ResourceType: block.Labels()[0], ResourceName: block.Labels()[1]}
+		if block.Body().GetAttribute("tags") == nil {
+			result.Outcome = policies.OUTCOME_FAIL
+			result.Reason = "Missing owner tag"
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func TestRegisterResourcePolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	RegisterResourcePolicy("owner_tag_policy", &ownerTagPolicy{})
+	defer delete(POLICY_MAPPING_RESOURCES, "owner_tag_policy")
+
+	dir := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(dir, ".terraform"), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), []byte(`
+resource "azurerm_resource_group" "tagged" {
+  tags = { owner = "platform" }
+}
+
+resource "azurerm_resource_group" "untagged" {
+}
+`), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terrapolicy.yaml"), []byte(`
+resources:
+  - id: owner-tag
+    type: owner_tag_policy
+    params:
+      resource: azurerm_*
+`), 0644)).To(Succeed())
+
+	policy, err := policies.Parse(filepath.Join(dir, ".terrapolicy.yaml"), Schemas())
+	g.Expect(err).To(BeNil())
+
+	result, err := TerraPolicy(Args{Policy: policy, Dir: dir})
+	g.Expect(err).To(MatchError("policy_failure"))
+	g.Expect(result.Findings).To(HaveLen(2))
+	g.Expect(result.Failures()).To(HaveLen(1))
+	g.Expect(result.Failures()[0].Result.Address()).To(Equal("azurerm_resource_group.untagged"))
+}
@@ -0,0 +1,509 @@
+package terrapolicy
+
+import (
+	"context"
+	"fmt"
+	"github.com/clearbank/terrapolicy/internals/file"
+	"github.com/clearbank/terrapolicy/internals/providers"
+	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"github.com/clearbank/terrapolicy/policies"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"log"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Options configures the evaluation of a policy against a root module
+type Options struct {
+	Policy policies.Policy
+	Flags  policies.PolicyExecutionFlags
+	Dir    string
+	// Schemas provides the resource type schemas. Defaults to the provider plugins
+	// installed in Dir by terraform init
+	Schemas policies.SchemaSource
+	// Parallelism is the number of files evaluated concurrently. Defaults to the number of CPUs
+	Parallelism int
+	// Now is the time waivers expire against. Defaults to the current time
+	Now time.Time
+}
+
+// Result holds the findings of every policy evaluation and the remediated
+// content of the files, which are only written by Write
+type Result struct {
+	Findings     []policies.Finding
+	Changes      []FileChange
+	StaleWaivers []policies.Waiver
+	Timing       Timing
+}
+
+// Timing measures the duration of each stage of a run
+type Timing struct {
+	Started   time.Time
+	Providers time.Duration
+	Resources time.Duration
+	Total     time.Duration
+}
+
+type PoliciesHandlerFunc func(ctx context.Context, options *Options, result *Result) error
+
+// Run evaluates the policy against the root module in options.Dir without
+// writing any file. Failed policies are reported as findings of the result; an
+// error, of type *Error, is only returned if the evaluation cannot complete, in
+// which case the result holds the findings collected so far
+func Run(ctx context.Context, options Options) (*Result, error) {
+	log.Printf("[INFO] starting terrapolicy")
+	result := &Result{Timing: Timing{Started: time.Now()}}
+	defer func() { result.Timing.Total = time.Since(result.Timing.Started) }()
+
+	// the provider plugins are not needed with another source of schemas, but
+	// the modules are only known once terraform init has run
+	if err := terraform.ValidateInitRun(options.Dir); err != nil {
+		if options.Schemas == nil {
+			return result, fail(err, "terraform_init")
+		}
+		log.Printf("[WARN] %v: the files of modules are not evaluated", err)
+	}
+
+	if options.Schemas == nil {
+		options.Schemas = tfschema.NewPluginSource(options.Dir)
+	}
+
+	stages := []struct {
+		handler  PoliciesHandlerFunc
+		duration *time.Duration
+	}{
+		{runProvidersPolicies, &result.Timing.Providers},
+		{runResourcePolicies, &result.Timing.Resources},
+	}
+
+	for _, stage := range stages {
+		started := time.Now()
+		err := stage.handler(ctx, &options, result)
+		*stage.duration = time.Since(started)
+
+		if err != nil {
+			return result, err
+		}
+	}
+
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	applyWaivers(&options, result, now)
+
+	for _, finding := range result.Findings {
+		if finding.Result.Outcome == policies.OUTCOME_WARN {
+			log.Printf("[WARN] policy %v could not evaluate %v: %v", finding.Policy.Describe(), location(finding), finding.Result.Reason)
+		}
+	}
+
+	for _, finding := range result.Failures() {
+		log.Printf("[WARN] %v policy %v failed on %v with reason: %v", finding.Policy.GetSeverity(), finding.Policy.Describe(), location(finding), finding.Result.Reason)
+		if finding.Policy.RemediationGuidance != "" {
+			log.Printf("[WARN] remediation guidance: %v", finding.Policy.RemediationGuidance)
+		}
+	}
+
+	return result, nil
+}
+
+func (o *Options) parallelism(files int) int {
+	parallelism := o.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	if parallelism > files {
+		parallelism = files
+	}
+	return parallelism
+}
+
+func (r Result) Failures() []policies.Finding {
+	var failures []policies.Finding
+	for _, finding := range r.Findings {
+		if finding.Result.Outcome == policies.OUTCOME_FAIL {
+			failures = append(failures, finding)
+		}
+	}
+	return failures
+}
+
+func (r Result) BlockingFailures(failOn policies.Severity) []policies.Finding {
+	var failures []policies.Finding
+	for _, finding := range r.Failures() {
+		if finding.Policy.GetSeverity().IsBlocking(failOn) {
+			failures = append(failures, finding)
+		}
+	}
+	return failures
+}
+
+// FileFindings groups the findings by file. Findings of provider policies are
+// grouped under an empty path
+func (r Result) FileFindings() map[string][]policies.Finding {
+	findings := make(map[string][]policies.Finding)
+	for _, finding := range r.Findings {
+		findings[finding.FilePath] = append(findings[finding.FilePath], finding)
+	}
+	return findings
+}
+
+func runProvidersPolicies(ctx context.Context, options *Options, result *Result) error {
+	log.Printf("[INFO] starting providers policies")
+
+	if len(options.Policy.Providers) == 0 {
+		return nil
+	}
+
+	providers, err := providerVersions(options)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] tf providers: %v", providers)
+
+	// locates the findings, for the reports
+	providerRanges, err := terraform.ProviderRanges(options.Dir)
+	if err != nil {
+		log.Printf("[WARN] cannot locate providers: %v", err)
+	}
+
+	for i, providerPolicy := range options.Policy.Providers {
+		if err := ctx.Err(); err != nil {
+			return fail(err, "cancelled")
+		}
+
+		policyHandler := POLICY_MAPPING_PROVIDERS[providerPolicy.Type]
+
+		if policyHandler == nil {
+			return fail(fmt.Errorf("cannot locate mapping for: %v", providerPolicy.Type), "missing_policy_type")
+		}
+
+		log.Printf("[INFO] processing policy %v", providerPolicy.Describe())
+		policyResults, err := policyHandler.Execute(policies.ProviderPolicyPayload{
+			Policy:           providerPolicy,
+			WorkingDir:       options.Dir,
+			Flags:            options.Flags,
+			CurrentProviders: providers,
+		})
+
+		if err != nil {
+			//any unhandled error should immediately stop execution
+			return fail(err, "policy_setup_failure")
+		}
+
+		for _, policyResult := range policyResults {
+			if policyResult.Outcome == policies.OUTCOME_REMEDIATE {
+				//provider policy cannot remediate
+				return fail(fmt.Errorf("policy %v cannot remediate providers", providerPolicy.Describe()), "policy_unabled_to_remediate")
+			}
+
+			finding := policies.Finding{
+				Policy:    providerPolicy,
+				PolicyRef: policies.Ref(policies.SECTION_PROVIDERS, i),
+				Result:    policyResult,
+			}
+			if r, ok := providerRanges[policyResult.ResourceName]; ok && policyResult.ResourceType == "provider" {
+				finding.FilePath = r.Filename
+				if finding.Result.Range.Filename == "" {
+					finding.Result.Range = r
+				}
+			}
+
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+	return nil
+}
+
+// providerVersions returns the versions of the providers selected in the lock
+// file, even if it locks none. The terraform cli reports them only when the root
+// module has no lock file, and reports the version of terraform itself, only run
+// if a policy checks it
+func providerVersions(options *Options) (map[string]providers.Version, error) {
+	lockedProviders, err := terraform.ReadLockFile(options.Dir)
+	if err != nil {
+		return nil, fail(err, "lock_file")
+	}
+
+	versions := make(map[string]providers.Version)
+	for _, locked := range lockedProviders {
+		versions[locked.Address] = providers.ParseStringVersion(locked.Version)
+	}
+
+	if lockedProviders != nil && !checksTerraform(options.Policy) {
+		return versions, nil
+	}
+
+	out, err := terraform.GetTerraformVersionOutput(options.Dir)
+
+	if err != nil {
+		return nil, fail(err, "terraform_output")
+	}
+
+	log.Printf("[DEBUG] tf output: %v", out)
+
+	cliVersions, err := providers.ParseTerraformOutput(&out)
+	if err != nil {
+		return nil, fail(err, "terraform_providers")
+	}
+
+	if lockedProviders == nil {
+		return cliVersions, nil
+	}
+
+	versions["terraform"] = cliVersions["terraform"]
+	return versions, nil
+}
+
+func checksTerraform(policy policies.Policy) bool {
+	for _, providerPolicy := range policy.Providers {
+		if providerPolicy.StringParam("provider") == "terraform" {
+			return true
+		}
+	}
+	return false
+}
+
+func runResourcePolicies(ctx context.Context, options *Options, result *Result) error {
+	log.Printf("[INFO] starting resource policies")
+	tfFiles, err := terraform.GetTerraformFiles(options.Dir)
+
+	if err != nil {
+		return fail(err, "read_files")
+	} else {
+		log.Printf("[DEBUG] files: %v", tfFiles)
+	}
+
+	// the providers required by each module resolve the provider of its resources
+	moduleProviders := make(map[string]*terraform.ModuleProviders)
+	for _, tfFile := range tfFiles {
+		dir := filepath.Dir(tfFile.Path)
+		if _, ok := moduleProviders[dir]; ok {
+			continue
+		}
+
+		if moduleProviders[dir], err = terraform.ReadModuleProviders(dir); err != nil {
+			return fail(err, "read_hcl_files")
+		}
+	}
+
+	// the first failing file stops the evaluation of the others
+	evalCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fileResults := make([]fileResult, len(tfFiles))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < options.parallelism(len(tfFiles)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fileResults[i] = evaluateFile(evalCtx, options, tfFiles[i], moduleProviders[filepath.Dir(tfFiles[i].Path)])
+				if fileResults[i].err != nil {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	for i := range tfFiles {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	// results are merged in file order, whatever the order they completed in
+	for _, evaluation := range fileResults {
+		if evaluation.err != nil {
+			return evaluation.err
+		}
+
+		result.Findings = append(result.Findings, evaluation.findings...)
+		if evaluation.change != nil {
+			result.Changes = append(result.Changes, *evaluation.change)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fail(err, "cancelled")
+	}
+
+	return nil
+}
+
+// fileResult holds the evaluation of the resource policies on a file. A file
+// is skipped, without error, once the evaluation is cancelled
+type fileResult struct {
+	findings []policies.Finding
+	change   *FileChange
+	err      error
+}
+
+func evaluateFile(ctx context.Context, options *Options, tfFile terraform.TerraformFile, moduleProviders *terraform.ModuleProviders) fileResult {
+	if ctx.Err() != nil {
+		return fileResult{}
+	}
+
+	path := tfFile.Path
+	relPath, _ := relativePath(options.Dir, path)
+	log.Printf("[INFO] processing %v", path)
+	hcl, err := file.ReadHCLFile(path)
+
+	if err != nil {
+		return fileResult{err: fail(err, "read_hcl_files")}
+	}
+	source := terraform.NewSourceIndex(path, hcl)
+	result, remediated := fileResult{findings: invalidBlockFindings(path, hcl, source)}, false
+
+	// the tokens of a rewritten file are all new: the findings of the rewriting
+	// policy after the rewrite, and of the following policies, are located in the
+	// remediated content. The file and its index are updated in place, as the
+	// payload of the rewriting policy holds them
+	rewrite := func(content []byte) error {
+		rewritten, err := file.ParseHCL(content, path)
+		if err != nil {
+			return err
+		}
+		*hcl, *source = *rewritten, *terraform.NewSourceIndex(path, rewritten)
+		return nil
+	}
+
+	for i, resourcePolicy := range options.Policy.Resources {
+		if ctx.Err() != nil {
+			return fileResult{}
+		}
+
+		policyHandler := POLICY_MAPPING_RESOURCES[resourcePolicy.Type]
+
+		if policyHandler == nil {
+			return fileResult{err: fail(fmt.Errorf("cannot locate mapping for %v", resourcePolicy.Type), "missing_policy_type")}
+		}
+
+		if !resourcePolicy.Scope.Matches(relPath, tfFile.Module) {
+			log.Printf("[DEBUG] %v out of scope of policy %v", relPath, resourcePolicy.Describe())
+			continue
+		}
+
+		log.Printf("[INFO] processing policy %v on %v", resourcePolicy.Describe(), path)
+		policyResults, err := policyHandler.Execute(policies.ResourcePolicyPayload{
+			Hcl:        hcl,
+			Source:     source,
+			Policy:     resourcePolicy,
+			FileName:   file.GetFilename(path),
+			FilePath:   path,
+			WorkingDir: options.Dir,
+			Flags:      options.Flags,
+			Rewrite:    rewrite,
+			Schemas:    options.Schemas,
+			Providers:  moduleProviders,
+		})
+
+		if err != nil {
+			//any unhandled error should immediately stop execution
+			return fileResult{err: fail(err, "policy_setup_failure")}
+		}
+
+		for _, policyResult := range policyResults {
+			if policyResult.Outcome == policies.OUTCOME_REMEDIATE {
+				remediated = true
+			}
+
+			result.findings = append(result.findings, policies.Finding{
+				Policy:    resourcePolicy,
+				PolicyRef: policies.Ref(policies.SECTION_RESOURCES, i),
+				FilePath:  path,
+				Result:    policyResult,
+			})
+		}
+	}
+
+	if remediated {
+		original, err := file.ReadFile(path)
+		if err != nil {
+			return fileResult{err: fail(err, "read_hcl_files")}
+		}
+
+		result.change = &FileChange{
+			Path:     path,
+			Original: string(original),
+			Content:  string(hcl.Bytes()),
+		}
+	}
+
+	return result
+}
+
+// INVALID_BLOCK_POLICY is the policy of the findings on the resource and data
+// blocks terraform rejects. Policies skip these blocks, the file is still evaluated
+var INVALID_BLOCK_POLICY = policies.PolicyBlock{
+	Id:          "invalid_block",
+	Type:        "invalid_block",
+	Description: "resource and data blocks require a type and a name label",
+}
+
+func invalidBlockFindings(path string, hcl *hclwrite.File, source *terraform.SourceIndex) []policies.Finding {
+	var findings []policies.Finding
+	for _, block := range terraform.InvalidResourceBlocks(hcl) {
+		r, _ := source.BlockRange(block)
+		log.Printf("[WARN] %v: %v block requires a type and a name label, got %v", r, block.Type(), block.Labels())
+		findings = append(findings, policies.Finding{
+			Policy:    INVALID_BLOCK_POLICY,
+			PolicyRef: INVALID_BLOCK_POLICY.Id,
+			FilePath:  path,
+			Result: policies.PolicyResult{
+				Outcome:      policies.OUTCOME_FAIL,
+				Reason:       fmt.Sprintf("%v block requires a type and a name label, got %v", block.Type(), block.Labels()),
+				ResourceType: terraform.GetResourceType(block),
+				ResourceName: terraform.GetResourceName(block),
+				Range:        r,
+			},
+		})
+	}
+	return findings
+}
+
+// applyWaivers waives the failures matched by a waiver that has not expired, and
+// collects the waivers that do not match any failure
+func applyWaivers(options *Options, result *Result, now time.Time) {
+	waivers := options.Policy.Waivers
+	used := make([]bool, len(waivers))
+
+	for i := range result.Findings {
+		finding := &result.Findings[i]
+		if finding.Result.Outcome != policies.OUTCOME_FAIL {
+			continue
+		}
+
+		relPath, _ := relativePath(options.Dir, finding.FilePath)
+		for j, waiver := range waivers {
+			if !waiver.Matches(*finding, relPath) {
+				continue
+			}
+
+			used[j] = true
+			if waiver.IsExpired(now) {
+				log.Printf("[WARN] waiver of policy `%v` on %v expired on %v", waiver.Policy, location(*finding), waiver.Expires)
+				continue
+			}
+
+			log.Printf("[INFO] policy %v on %v %v", finding.Policy.Describe(), location(*finding), waiver.Describe())
+			finding.Result.Outcome = policies.OUTCOME_WAIVED
+			finding.Result.Reason = waiver.Describe()
+			break
+		}
+	}
+
+	for j, waiver := range waivers {
+		if !used[j] {
+			log.Printf("[WARN] stale waiver of policy `%v` does not match any failure", waiver.Policy)
+			result.StaleWaivers = append(result.StaleWaivers, waiver)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package terrapolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/clearbank/terrapolicy/policies"
+	"github.com/zclconf/go-cty/cty"
+
+	. "github.com/onsi/gomega"
+)
+
+// tlsPolicy remediates without provider schema
+type tlsPolicy struct{}
+
+func (p *tlsPolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{}
+}
+
+func (p *tlsPolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	var results []policies.PolicyResult
+	for _, block := range payload.Hcl.Body().Blocks() {
+		if len(block.Labels()) != 2 {
+			continue
+		}
+		block.Body().SetAttributeValue("min_tls_version", cty.StringVal("TLS1_2"))
+		results = append(results, policies.PolicyResult{
+			ResourceType: block.Labels()[0],
+			ResourceName: block.Labels()[1],
+			Outcome:      policies.OUTCOME_REMEDIATE,
+		})
+	}
+	return results, nil
+}
+
+// rewritePolicy replaces the whole file, as plugins do, and locates its first block
+type rewritePolicy struct{}
+
+func (p *rewritePolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{}
+}
+
+func (p *rewritePolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	content := append([]byte("# rewritten\n"), payload.Hcl.Bytes()...)
+	if err := payload.Rewrite(content); err != nil {
+		return nil, err
+	}
+	result := policies.PolicyResult{ResourceType: "file", ResourceName: payload.FileName, Outcome: policies.OUTCOME_REMEDIATE}
+	result.Range, _ = payload.Source.BlockRange(payload.Hcl.Body().Blocks()[0])
+	return []policies.PolicyResult{result}, nil
+}
+
+// locatePolicy fails every block, at its range
+type locatePolicy struct{}
+
+func (p *locatePolicy) Schema() policies.PolicySchema {
+	return policies.PolicySchema{}
+}
+
+func (p *locatePolicy) Execute(payload policies.ResourcePolicyPayload) ([]policies.PolicyResult, error) {
+	var results []policies.PolicyResult
+	for _, block := range payload.Hcl.Body().Blocks() {
+		result := policies.PolicyResult{ResourceType: block.Labels()[0], ResourceName: block.Labels()[1], Outcome: policies.OUTCOME_FAIL}
+		result.Range, _ = payload.Source.BlockRange(block)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+const runTestFile = `resource "azurerm_storage_account" "storage" {
+  name = "storage"
+}
+`
+
+func setupRun(t *testing.T) (string, policies.Policy) {
+	g := NewWithT(t)
+
+	RegisterResourcePolicy("tls_policy", &tlsPolicy{})
+	t.Cleanup(func() { delete(POLICY_MAPPING_RESOURCES, "tls_policy") })
+
+	dir := t.TempDir()
+	g.Expect(os.Mkdir(filepath.Join(dir, ".terraform"), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), []byte(runTestFile), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terrapolicy.yaml"), []byte(`
+resources:
+  - id: storage-tls
+    type: tls_policy
+`), 0644)).To(Succeed())
+
+	policy, err := policies.Parse(filepath.Join(dir, ".terrapolicy.yaml"), Schemas())
+	g.Expect(err).To(BeNil())
+
+	return dir, policy
+}
+
+func TestRun(t *testing.T) {
+	g := NewWithT(t)
+	dir, policy := setupRun(t)
+
+	result, err := Run(context.Background(), Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Findings).To(HaveLen(1))
+	g.Expect(result.Findings[0].Result.Outcome).To(Equal(policies.OUTCOME_REMEDIATE))
+	g.Expect(result.FileFindings()).To(HaveKey(result.Findings[0].FilePath))
+	g.Expect(result.Timing.Total).To(BeNumerically(">", 0))
+
+	g.Expect(result.Changes).To(HaveLen(1))
+	g.Expect(result.Changes[0].Original).To(Equal(runTestFile))
+	g.Expect(result.Changes[0].Content).To(ContainSubstring(`min_tls_version = "TLS1_2"`))
+
+	content, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(content)).To(Equal(runTestFile))
+
+	g.Expect(result.Write(WriteOptions{Dir: dir, Strategy: WRITE_STRATEGY_IN_PLACE})).To(Succeed())
+
+	content, err = os.ReadFile(filepath.Join(dir, "main.tf"))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(content)).To(ContainSubstring(`min_tls_version = "TLS1_2"`))
+}
+
+func TestRunCancelled(t *testing.T) {
+	g := NewWithT(t)
+	dir, policy := setupRun(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Run(ctx, Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(MatchError("cancelled"))
+
+	var runErr *Error
+	g.Expect(errors.As(err, &runErr)).To(BeTrue())
+	g.Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+}
+
+func TestWriteInvalidStrategy(t *testing.T) {
+	g := NewWithT(t)
+
+	result := &Result{}
+	g.Expect(result.Write(WriteOptions{Strategy: WRITE_STRATEGY_MIRROR})).To(MatchError("missing_out_dir"))
+	g.Expect(result.Write(WriteOptions{Strategy: "unknown"})).To(MatchError("unknown_write_strategy"))
+}
+
+func TestRunInvalidResourceBlock(t *testing.T) {
+	g := NewWithT(t)
+	dir, policy := setupRun(t)
+	g.Expect(os.WriteFile(filepath.Join(dir, "invalid.tf"), []byte("resource \"azurerm_storage_account\" {}\n"), 0644)).To(Succeed())
+
+	result, err := Run(context.Background(), Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(BeNil())
+
+	// the malformed block is reported and the other files still evaluated
+	var invalid []policies.Finding
+	for _, finding := range result.Findings {
+		if finding.Policy.Type == INVALID_BLOCK_POLICY.Type {
+			invalid = append(invalid, finding)
+		}
+	}
+	g.Expect(invalid).To(HaveLen(1))
+	g.Expect(invalid[0].FilePath).To(Equal(filepath.Join(dir, "invalid.tf")))
+	g.Expect(invalid[0].Result.Outcome).To(Equal(policies.OUTCOME_FAIL))
+	g.Expect(invalid[0].Result.Reason).To(Equal("resource block requires a type and a name label, got [azurerm_storage_account]"))
+	g.Expect(invalid[0].Result.Range.Start.Line).To(Equal(1))
+	g.Expect(result.Changes).To(HaveLen(1))
+	g.Expect(result.Changes[0].Path).To(Equal(filepath.Join(dir, "main.tf")))
+}
+
+func TestWriteMirror(t *testing.T) {
+	g := NewWithT(t)
+	dir, policy := setupRun(t)
+	out := filepath.Join(t.TempDir(), "out")
+
+	result, err := Run(context.Background(), Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Changes).To(HaveLen(1))
+
+	g.Expect(os.MkdirAll(filepath.Join(dir, ".terraform", "providers"), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terraform", "providers", "terraform-provider"), []byte("binary"), 0755)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf.bak"), []byte(runTestFile), 0644)).To(Succeed())
+
+	// a local module outside of the root module
+	outside := filepath.Join(t.TempDir(), "main.tf")
+	result.Changes = append(result.Changes, FileChange{Path: outside, Original: runTestFile, Content: runTestFile + "# remediated\n"})
+
+	g.Expect(result.Write(WriteOptions{Dir: dir, Strategy: WRITE_STRATEGY_MIRROR, Out: out})).To(Succeed())
+
+	mirrored, err := os.ReadFile(filepath.Join(out, "main.tf"))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(mirrored)).To(ContainSubstring(`min_tls_version = "TLS1_2"`))
+	g.Expect(filepath.Join(out, ".terrapolicy.yaml")).To(BeAnExistingFile())
+	g.Expect(filepath.Join(out, ".terraform")).NotTo(BeAnExistingFile())
+	g.Expect(filepath.Join(out, "main.tf.bak")).NotTo(BeAnExistingFile())
+
+	original, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	g.Expect(err).To(BeNil())
+	g.Expect(string(original)).To(Equal(runTestFile))
+	g.Expect(outside).NotTo(BeAnExistingFile())
+
+	// files of a previous run would be stale
+	g.Expect(result.Write(WriteOptions{Dir: dir, Strategy: WRITE_STRATEGY_MIRROR, Out: out})).To(MatchError("out_dir_not_empty"))
+}
+
+func TestRunRewrite(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := setupRun(t)
+
+	RegisterResourcePolicy("rewrite_policy", &rewritePolicy{})
+	RegisterResourcePolicy("locate_policy", &locatePolicy{})
+	t.Cleanup(func() {
+		delete(POLICY_MAPPING_RESOURCES, "rewrite_policy")
+		delete(POLICY_MAPPING_RESOURCES, "locate_policy")
+	})
+
+	policy := policies.Policy{Resources: []policies.PolicyBlock{{Type: "rewrite_policy"}, {Type: "locate_policy"}}}
+	result, err := Run(context.Background(), Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Changes).To(HaveLen(1))
+	g.Expect(result.Changes[0].Content).To(HavePrefix("# rewritten\n"))
+
+	// located in the rewritten content, by the rewriting policy and the following ones
+	g.Expect(result.Findings[0].Result.Range.Start.Line).To(Equal(2))
+	g.Expect(result.Failures()).To(HaveLen(1))
+	g.Expect(result.Failures()[0].Result.Range.Start.Line).To(Equal(2))
+}
+
+func TestRunParallel(t *testing.T) {
+	g := NewWithT(t)
+	dir, policy := setupRun(t)
+
+	for i := 0; i < 20; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("storage_%02d.tf", i))
+		g.Expect(os.WriteFile(name, []byte(runTestFile), 0644)).To(Succeed())
+	}
+
+	serial, err := Run(context.Background(), Options{Policy: policy, Dir: dir, Parallelism: 1})
+	g.Expect(err).To(BeNil())
+	g.Expect(serial.Findings).To(HaveLen(21))
+
+	for i := 0; i < 5; i++ {
+		parallel, err := Run(context.Background(), Options{Policy: policy, Dir: dir, Parallelism: 8})
+		g.Expect(err).To(BeNil())
+		g.Expect(parallel.Findings).To(Equal(serial.Findings))
+		g.Expect(parallel.Changes).To(Equal(serial.Changes))
+	}
+}
+
+func TestRunSchemaFile(t *testing.T) {
+	g := NewWithT(t)
+
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(filepath.Join(dir, "main.tf"), []byte(runTestFile), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, "schemas.json"), []byte(`{
+  "format_version": "1.0",
+  "provider_schemas": {
+    "registry.terraform.io/hashicorp/azurerm": {
+      "resource_schemas": {
+        "azurerm_storage_account": {
+          "block": {
+            "attributes": {
+              "name": {"type": "string", "required": true},
+              "min_tls_version": {"type": "string", "optional": true}
+            }
+          }
+        }
+      }
+    }
+  }
+}`), 0644)).To(Succeed())
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terrapolicy.yaml"), []byte(`
+resources:
+  - id: storage-tls
+    type: attributes_policy
+    params:
+      resource: azurerm_storage_account
+      attribute: min_tls_version
+      value: TLS1_2
+      strategy: force_set
+`), 0644)).To(Succeed())
+
+	policy, err := policies.Parse(filepath.Join(dir, ".terrapolicy.yaml"), Schemas())
+	g.Expect(err).To(BeNil())
+
+	schemas, err := LoadSchemaFile(filepath.Join(dir, "schemas.json"))
+	g.Expect(err).To(BeNil())
+
+	result, err := Run(context.Background(), Options{Policy: policy, Dir: dir, Schemas: schemas})
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Findings).To(HaveLen(1))
+	g.Expect(result.Findings[0].Result.Outcome).To(Equal(policies.OUTCOME_REMEDIATE))
+	g.Expect(result.Changes[0].Content).To(ContainSubstring(`min_tls_version = "TLS1_2"`))
+}
+
+func TestRunLockedProviderVersions(t *testing.T) {
+	g := NewWithT(t)
+	dir, _ := setupRun(t)
+
+	// the versions are read from the lock file, without terraform
+	t.Setenv("PATH", "")
+
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte(`
+provider "registry.terraform.io/hashicorp/azurerm" {
+  version = "3.44.1"
+}
+`), 0644)).To(Succeed())
+
+	policy := policies.Policy{Providers: []policies.PolicyBlock{{
+		Type: "version_policy",
+		Params: map[string]interface{}{
+			"provider": "registry.terraform.io/hashicorp/azurerm",
+			"value":    ">= 3.40, != 3.44.1",
+			"strategy": "constraint",
+		},
+	}}}
+
+	result, err := Run(context.Background(), Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(BeNil())
+	g.Expect(result.Failures()).To(HaveLen(1))
+	g.Expect(result.Failures()[0].Result.Reason).To(Equal("Version 3.44.1 does not meet constraint >= 3.40, != 3.44.1"))
+	g.Expect(result.Failures()[0].FilePath).To(Equal(filepath.Join(dir, ".terraform.lock.hcl")))
+	g.Expect(result.Failures()[0].Result.Range.Start.Line).To(Equal(2))
+
+	policy.Providers[0].Params["provider"] = "terraform"
+	_, err = Run(context.Background(), Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(MatchError("terraform_output"))
+
+	// a lock file without providers does not fall back to terraform either
+	g.Expect(os.WriteFile(filepath.Join(dir, ".terraform.lock.hcl"), []byte("\n"), 0644)).To(Succeed())
+	policy.Providers[0].Params["provider"] = "registry.terraform.io/hashicorp/azurerm"
+	_, err = Run(context.Background(), Options{Policy: policy, Dir: dir})
+	g.Expect(err).To(BeNil())
+}
@@ -1,157 +1,226 @@
 package terrapolicy
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"github.com/clearbank/terrapolicy/internals/file"
-	"github.com/clearbank/terrapolicy/internals/policies"
-	"github.com/clearbank/terrapolicy/internals/policies/providers"
-	"github.com/clearbank/terrapolicy/internals/policies/resources"
-	"github.com/clearbank/terrapolicy/internals/providers"
-	"github.com/clearbank/terrapolicy/internals/terraform"
+	"github.com/clearbank/terrapolicy/internals/tfschema"
+	"github.com/clearbank/terrapolicy/plugin"
+	"github.com/clearbank/terrapolicy/policies"
+	"github.com/clearbank/terrapolicy/policies/providers"
+	"github.com/clearbank/terrapolicy/policies/resources"
 	"log"
-
-	"github.com/hashicorp/hcl/v2/hclwrite"
+	"path/filepath"
+	"strings"
 )
 
 type Args struct {
-	Policy policies.Policy
-	Flags  policies.PolicyExecutionFlags
-	Dir    string
+	Policy        policies.Policy
+	Flags         policies.PolicyExecutionFlags
+	Dir           string
+	DryRun        bool
+	WriteStrategy string
+	Out           string
+	FailOn        policies.Severity
+	Parallelism   int
+	SchemaFile    string
 }
 
-type PoliciesHandlerFunc func(args *Args) error
-
 var POLICY_MAPPING_RESOURCES = map[string]policies.ResourcePolicyExecutor{
 	"attributes_policy": &resource_policies.AttributesPolicy{},
 }
 var POLICY_MAPPING_PROVIDERS = map[string]policies.ProviderPolicyExecutor{
-	"version_policy": &provider_policies.VersionPolicy{},
+	"version_policy":   &provider_policies.VersionPolicy{},
+	"lock_file_policy": &provider_policies.LockFilePolicy{},
 }
 
-func TerraPolicy(args Args) error {
-	log.Printf("[INFO] starting terrapolicy")
+// RegisterResourcePolicy registers the executor of a resource policy type,
+// replacing the executor already registered for the type if any
+func RegisterResourcePolicy(policyType string, executor policies.ResourcePolicyExecutor) {
+	POLICY_MAPPING_RESOURCES[policyType] = executor
+}
 
-	if err := terraform.ValidateInitRun(args.Dir); err != nil {
-		return fail(err, "terraform_init")
+// RegisterProviderPolicy registers the executor of a provider policy type,
+// replacing the executor already registered for the type if any
+func RegisterProviderPolicy(policyType string, executor policies.ProviderPolicyExecutor) {
+	POLICY_MAPPING_PROVIDERS[policyType] = executor
+}
+
+// LoadPlugins launches the plugins declared by the policy at path, and registers
+// the resource policy types they serve. plugin.Cleanup stops the plugins
+func LoadPlugins(path string) error {
+	policy, _, err := policies.Lint(path, policies.Schemas{})
+	if err != nil {
+		return err
 	}
 
-	for _, handler := range []PoliciesHandlerFunc{runProvidersPolicies, runResourcePolicies} {
-		if err := handler(&args); err != nil {
-			return err
+	for _, config := range policy.Plugins {
+		if config.Path == "" {
+			continue // reported when parsing the policy
+		}
+
+		client, err := plugin.Load(config)
+		if err != nil {
+			return fail(err, "plugin_load")
+		}
+
+		executors, err := client.Executors()
+		if err != nil {
+			return fail(err, "plugin_load")
+		}
+
+		for policyType, executor := range executors {
+			log.Printf("[INFO] registering policy type `%v` of plugin %v", policyType, config.Describe())
+			RegisterResourcePolicy(policyType, executor)
 		}
 	}
 
 	return nil
 }
 
-func runProvidersPolicies(args *Args) error {
-	log.Printf("[INFO] starting providers policies")
-
-	out, err := terraform.GetTerraformVersionOutput(args.Dir)
+// SetSchemaCache sets the directory provider schemas are cached in, across runs
+// and root modules. The cache is disabled when dir is empty, as by default
+func SetSchemaCache(dir string) {
+	tfschema.SetCacheDir(dir)
+}
 
+// LoadSchemaFile loads the resource type schemas of a file output by
+// `terraform providers schema -json`, to run without provider plugins
+func LoadSchemaFile(path string) (policies.SchemaSource, error) {
+	source, err := tfschema.LoadSchemaFile(path)
 	if err != nil {
-		return fail(err, "terraform_output")
+		return nil, fail(err, "schema_file")
 	}
+	return source, nil
+}
 
-	log.Printf("[DEBUG] tf output: %v", out)
+// DefaultSchemaCache returns the terrapolicy directory of the user cache directory
+func DefaultSchemaCache() string {
+	return tfschema.DefaultCacheDir()
+}
 
-	providers, err := providers.ParseTerraformOutput(&out)
-	if err != nil {
-		return fail(err, "terraform_providers")
+// Schemas returns the parameter schemas of the policy types
+func Schemas() policies.Schemas {
+	schemas := policies.Schemas{
+		Providers: make(map[string]policies.PolicySchema),
+		Resources: make(map[string]policies.PolicySchema),
+	}
+
+	for name, executor := range POLICY_MAPPING_PROVIDERS {
+		schemas.Providers[name] = executor.Schema()
+	}
+	for name, executor := range POLICY_MAPPING_RESOURCES {
+		schemas.Resources[name] = executor.Schema()
 	}
 
-	log.Printf("[INFO] tf providers: %v", providers)
+	return schemas
+}
 
-	for _, providerPolicy := range args.Policy.Providers {
-		policyHandler := POLICY_MAPPING_PROVIDERS[providerPolicy.Type]
+// TerraPolicy runs the policy and writes the remediated files as configured by
+// args. It fails when a failure is at or above args.FailOn, or when files would
+// be remediated in a dry run
+func TerraPolicy(args Args) (Result, error) {
+	writeOptions := args.writeOptions()
+	if err := writeOptions.validate(); err != nil {
+		return Result{}, err
+	}
 
-		if policyHandler == nil {
-			return fail(fmt.Errorf("cannot locate mapping for: %v", providerPolicy.Type), "missing_policy_type")
+	options := Options{
+		Policy:      args.Policy,
+		Flags:       args.Flags,
+		Dir:         args.Dir,
+		Parallelism: args.Parallelism,
+	}
+
+	if args.SchemaFile != "" {
+		schemas, err := LoadSchemaFile(args.SchemaFile)
+		if err != nil {
+			return Result{}, err
 		}
+		options.Schemas = schemas
+	}
 
-		log.Printf("[INFO] processing policy `%v`", providerPolicy.Type)
-		if result, err := policyHandler.Execute(policies.ProviderPolicyPayload{
-			Policy:           providerPolicy,
-			WorkingDir:       args.Dir,
-			Flags:            args.Flags,
-			CurrentProviders: providers,
-		}); err != nil {
-			//any unhandled error should immediately stop execution
-			return fail(err, "policy_setup_failure")
-		} else if result.Outcome == policies.OUTCOME_FAIL {
-			//maybe consider in the future grouping failed policies instead of terminating
-			return warn(fmt.Errorf("policy failed with reason: %v", result.Reason), "policy_failure")
-		} else if result.Outcome == policies.OUTCOME_REMEDIATE {
-			//provider policy cannot remediate
-			return fail(err, "policy_unabled_to_remediate")
+	result, err := Run(context.Background(), options)
+	if err != nil {
+		return *result, err
+	}
+
+	if blocking := result.BlockingFailures(args.FailOn); len(blocking) > 0 {
+		return *result, warn(fmt.Errorf("%v policy evaluation(s) failed with severity %v or above", len(blocking), args.FailOn.OrLowest()), "policy_failure")
+	}
+
+	if args.DryRun {
+		if len(result.Changes) > 0 {
+			return *result, warn(fmt.Errorf("%v file(s) would be remediated", len(result.Changes)), "remediation_pending")
 		}
+		return *result, nil
 	}
-	return nil
+
+	if err := result.Write(writeOptions); err != nil {
+		return *result, err
+	}
+
+	return *result, nil
 }
 
-func runResourcePolicies(args *Args) error {
-	log.Printf("[INFO] starting resource policies")
-	paths, err := terraform.GetTerraformFilePaths(args.Dir)
+// writeOptions resolves the write strategy and output directory. Arguments take precedence over the policy settings
+func (args *Args) writeOptions() WriteOptions {
+	options := WriteOptions{Dir: args.Dir, Strategy: args.WriteStrategy, Out: args.Out}
+	if options.Strategy == "" {
+		options.Strategy = args.Policy.Remediation.WriteStrategy
+	}
+	if options.Out == "" {
+		options.Out = args.Policy.Remediation.Out
+	}
+	return options
+}
 
+// relativePath returns path relative to the root module, and whether path is within it
+func relativePath(dir string, path string) (string, bool) {
+	rootDir, err := filepath.EvalSymlinks(dir)
 	if err != nil {
-		return fail(err, "read_files")
-	} else {
-		log.Printf("[DEBUG] paths: %v", paths)
+		return path, false
 	}
 
-	remediations := make(map[string]*hclwrite.File)
-	for _, path := range paths {
-		log.Printf("[INFO] processing %v", path)
-		hcl, err := file.ReadHCLFile(path)
-
-		if err != nil {
-			return fail(err, "read_hcl_files")
-		}
+	// module files are resolved, e.g. local modules linked from .terraform/modules
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
 
-		for _, resourcePolicy := range args.Policy.Resources {
-			policyHandler := POLICY_MAPPING_RESOURCES[resourcePolicy.Type]
-
-			if policyHandler == nil {
-				return fail(fmt.Errorf("cannot locate mapping for %v", resourcePolicy.Type), "missing_policy_type")
-			}
-
-			log.Printf("[INFO] processing policy `%v`", resourcePolicy.Type)
-			if result, err := policyHandler.Execute(policies.ResourcePolicyPayload{
-				Hcl:        hcl,
-				Policy:     resourcePolicy,
-				FileName:   file.GetFilename(path),
-				FilePath:   path,
-				WorkingDir: args.Dir,
-				Flags:      args.Flags,
-			}); err != nil {
-				//any unhandled error should immediately stop execution
-				return fail(err, "policy_setup_failure")
-			} else if result.Outcome == policies.OUTCOME_FAIL {
-				//maybe consider in the future grouping failed policies instead of terminating
-				return warn(fmt.Errorf("policy failed with reason: %v", result.Reason), "policy_failure")
-			} else if result.Outcome == policies.OUTCOME_REMEDIATE {
-				remediations[path] = hcl
-			}
-		}
+	rel, err := filepath.Rel(rootDir, path)
+	if err != nil {
+		return path, false
 	}
 
-	for path, hcl := range remediations {
-		text := string(hcl.Bytes())
-		if err := file.ReplaceWithTerrapolicyFile(path, text, true); err != nil {
-			return fail(err, "policy_remediation_failure")
-		}
+	return filepath.ToSlash(rel), !strings.HasPrefix(rel, "..")
+}
+
+func location(finding policies.Finding) string {
+	if finding.FilePath == "" {
+		return finding.Result.Address()
 	}
+	return finding.FilePath + ":" + finding.Result.Address()
+}
 
-	return nil
+// Error is returned when terrapolicy cannot complete, identified by its code
+type Error struct {
+	Code string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
 }
 
 func fail(e error, code string) error {
 	log.Printf("[ERROR] %v", e)
-	return errors.New(code)
+	return &Error{Code: code, Err: e}
 }
 
 func warn(e error, code string) error {
 	log.Printf("[WARN] %v", e)
-	return errors.New(code)
+	return &Error{Code: code, Err: e}
 }
@@ -11,7 +11,7 @@ import (
 
 	"github.com/clearbank/terrapolicy/internals/cli"
 	"github.com/clearbank/terrapolicy/internals/file"
-	"github.com/clearbank/terrapolicy/internals/policies"
+	"github.com/clearbank/terrapolicy/policies"
 
 	"log"
 
@@ -86,15 +86,25 @@ func itShouldRunTerraPolicy(suite *TestSuite, g *WithT, l Logger) {
 	g.Expect(err).To(BeNil(), "arguments failed to parse")
 
 	l.Log(stringArgs, cliArgs)
-	p, err := policies.Parse(cliArgs.Config)
+	g.Expect(LoadPlugins(cliArgs.Config)).To(Succeed(), "plugins failed to load")
+
+	p, err := policies.Parse(cliArgs.Config, Schemas())
 	g.Expect(err).To(BeNil(), "policy failed to parse")
 
-	err = TerraPolicy(Args{
-		Policy: p,
-		Flags: policies.PolicyExecutionFlags{
-			Strict: cliArgs.Strict,
-		},
-		Dir: cliArgs.Dir,
+	if cliArgs.Waivers != "" {
+		waivers, err := policies.ParseWaivers(cliArgs.Waivers)
+		g.Expect(err).To(BeNil(), "waivers failed to parse")
+		p.Waivers = append(p.Waivers, waivers...)
+	}
+
+	_, err = TerraPolicy(Args{
+		Policy:        p,
+		Flags:         cliArgs.ExecutionFlags(),
+		Dir:           cliArgs.Dir,
+		DryRun:        cliArgs.DryRun,
+		WriteStrategy: cliArgs.WriteStrategy,
+		Out:           cliArgs.Out,
+		FailOn:        cliArgs.FailOn,
 	})
 
 	g.Expect(err == nil).To(BeEquivalentTo(suite.pass), "wrong expected outcome")
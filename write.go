@@ -0,0 +1,105 @@
+package terrapolicy
+
+import (
+	"fmt"
+	"github.com/clearbank/terrapolicy/internals/file"
+	"log"
+	"path/filepath"
+)
+
+const (
+	WRITE_STRATEGY_IN_PLACE = "in_place"
+	WRITE_STRATEGY_SIDECAR  = "sidecar"
+	WRITE_STRATEGY_MIRROR   = "mirror"
+)
+
+// FileChange holds the remediated content of a file alongside its original content
+type FileChange struct {
+	Path     string
+	Original string
+	Content  string
+}
+
+// WriteOptions configures how the remediated files are written
+type WriteOptions struct {
+	// Dir is the root module the changes were computed against
+	Dir string
+	// Strategy defaults to WRITE_STRATEGY_SIDECAR
+	Strategy string
+	// Out is the output directory of WRITE_STRATEGY_MIRROR
+	Out string
+}
+
+func (c FileChange) Diff() (string, error) {
+	return file.UnifiedDiff(c.Path, c.Original, c.Content)
+}
+
+// Write writes the remediated files of the result
+func (r *Result) Write(options WriteOptions) error {
+	if err := options.validate(); err != nil {
+		return err
+	}
+
+	switch options.strategy() {
+	case WRITE_STRATEGY_SIDECAR:
+		for _, change := range r.Changes {
+			if err := file.ReplaceWithTerrapolicyFile(change.Path, change.Content, true); err != nil {
+				return fail(err, "policy_remediation_failure")
+			}
+		}
+	case WRITE_STRATEGY_IN_PLACE:
+		for _, change := range r.Changes {
+			if err := file.WriteFile(change.Path, change.Content); err != nil {
+				return fail(err, "policy_remediation_failure")
+			}
+		}
+	case WRITE_STRATEGY_MIRROR:
+		if err := file.Mirror(options.Dir, options.Out); err != nil {
+			return fail(err, "policy_remediation_failure")
+		}
+
+		for _, change := range r.Changes {
+			// local modules outside of the root module are not mirrored
+			rel, ok := relativePath(options.Dir, change.Path)
+			if !ok {
+				log.Printf("[WARN] skipping remediation of %v as it is outside of %v", change.Path, options.Dir)
+				continue
+			}
+
+			if err := file.WriteFile(filepath.Join(options.Out, rel), change.Content); err != nil {
+				return fail(err, "policy_remediation_failure")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o WriteOptions) strategy() string {
+	if o.Strategy == "" {
+		return WRITE_STRATEGY_SIDECAR
+	}
+	return o.Strategy
+}
+
+func (o WriteOptions) validate() error {
+	switch strategy := o.strategy(); strategy {
+	case WRITE_STRATEGY_SIDECAR, WRITE_STRATEGY_IN_PLACE:
+		return nil
+	case WRITE_STRATEGY_MIRROR:
+		if o.Out == "" {
+			return fail(fmt.Errorf("write strategy `%v` requires an output directory", strategy), "missing_out_dir")
+		}
+		// files left in out by a previous run would disagree with the sources
+		empty, err := file.IsEmptyDir(o.Out)
+		if err != nil {
+			return fail(err, "out_dir_not_empty")
+		}
+		if !empty {
+			return fail(fmt.Errorf("output directory %v of write strategy `%v` must be empty", o.Out, strategy), "out_dir_not_empty")
+		}
+		return nil
+	default:
+		return fail(fmt.Errorf("unknown write strategy: %v", strategy), "unknown_write_strategy")
+	}
+}